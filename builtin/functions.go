@@ -1,47 +1,81 @@
 package builtin
 
 import (
-	"fmt"
 	"github.com/raoulvdberge/risp/lexer"
 	"github.com/raoulvdberge/risp/parser"
 	"github.com/raoulvdberge/risp/runtime"
+	"github.com/raoulvdberge/risp/strings"
 	"github.com/raoulvdberge/risp/util"
 	"math/big"
+	"unicode/utf8"
 )
 
 var Symbols = runtime.Symtab{
-	"t":           runtime.NewSymbol(runtime.True),
-	"f":           runtime.NewSymbol(runtime.False),
-	"nil":         runtime.NewSymbol(runtime.Nil),
-	"print":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPrint, "print"))),
-	"println":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPrintln, "println"))),
-	"list":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinList, "list"))),
-	"string":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinString, "string"))),
-	"+":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "+"))),
-	"-":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "-"))),
-	"*":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "*"))),
-	"/":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "/"))),
-	"=":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinEquals, "="))),
-	"!=":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinNotEquals, "!="))),
-	">":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, ">"))),
-	">=":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, ">="))),
-	"<":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, "<"))),
-	"<=":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, "<="))),
-	"and":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinAnd, "and"))),
-	"or":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinOr, "or"))),
-	"not":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinNot, "not"))),
-	"call":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCall, "call"))),
-	"eval":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinEval, "eval"))),
-	"quoted2list": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinQuoted2List, "quoted2list"))),
-	"pass":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPass, "pass"))),
-	"load":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinLoad, "load"))),
-	"cat":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCat, "cat"))),
-	"assert":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinAssert, "assert"))),
+	"t":             runtime.NewSymbol(runtime.True),
+	"f":             runtime.NewSymbol(runtime.False),
+	"nil":           runtime.NewSymbol(runtime.Nil),
+	"print":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPrint, "print"))),
+	"println":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPrintln, "println"))),
+	"printf":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPrintf, "printf"))),
+	"printfln":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPrintf, "printfln"))),
+	"list":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinList, "list"))),
+	"vector":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinVector, "vector"))),
+	"vector?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinIsVector, "vector?"))),
+	"list->vector":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinListToVector, "list->vector"))),
+	"vector->list":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinVectorToList, "vector->list"))),
+	"cons":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCons, "cons"))),
+	"car":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCar, "car"))),
+	"cdr":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCdr, "cdr"))),
+	"string":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinString, "string"))),
+	"+":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "+"))),
+	"-":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "-"))),
+	"*":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "*"))),
+	"/":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMath, "/"))),
+	"=":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinEquals, "="))),
+	"!=":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinNotEquals, "!="))),
+	"compare":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCompare, "compare"))),
+	"hash":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinHash, "hash"))),
+	">":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, ">"))),
+	">=":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, ">="))),
+	"<":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, "<"))),
+	"<=":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinMathCmp, "<="))),
+	"and":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinAnd, "and"))),
+	"or":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinOr, "or"))),
+	"not":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinNot, "not"))),
+	"call":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCall, "call"))),
+	"eval":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinEval, "eval"))),
+	"quoted2list":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinQuoted2List, "quoted2list"))),
+	"pass":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinPass, "pass"))),
+	"load":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinLoad, "load"))),
+	"cat":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCat, "cat"))),
+	"assert":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinAssert, "assert"))),
+	"char->integer": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinCharToInteger, "char->integer"))),
+	"integer->char": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinIntegerToChar, "integer->char"))),
+	"string->list":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinStringToList, "string->list"))),
+	"stdin":         runtime.NewSymbol(runtime.NewPortValue(runtime.Stdin)),
+	"stdout":        runtime.NewSymbol(runtime.NewPortValue(runtime.Stdout)),
+	"stderr":        runtime.NewSymbol(runtime.NewPortValue(runtime.Stderr)),
+	"read-line":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinReadLine, "read-line"))),
+	"read-char":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinReadChar, "read-char"))),
+	"read-all":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinReadAll, "read-all"))),
+	"write":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinWrite, "write"))),
+	"flush":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinFlush, "flush"))),
+	"close":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinClose, "close"))),
+	"read":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinRead, "read"))),
+	"read-string":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinReadString, "read-string"))),
+	"read-forms":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinReadForms, "read-forms"))),
+	"error":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinError, "error"))),
+	"error?":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinIsError, "error?"))),
+	"error-kind":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinErrorKind, "error-kind"))),
+	"error-message": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinErrorMessage, "error-message"))),
+	"list->string":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(builtinListToString, "list->string"))),
 }
 
 func builtinPrint(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	for _, arg := range context.Args {
-		fmt.Print(arg)
+		if err := runtime.CurrentOutput().Write(arg.String()); err != nil {
+			return runtime.NewIOErrorValue(err), nil
+		}
 	}
 
 	return runtime.Nil, nil
@@ -49,20 +83,45 @@ func builtinPrint(context *runtime.FunctionCallContext) (*runtime.Value, error)
 
 func builtinPrintln(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	for _, arg := range context.Args {
-		fmt.Println(arg)
+		if err := runtime.CurrentOutput().Write(arg.String() + "\n"); err != nil {
+			return runtime.NewIOErrorValue(err), nil
+		}
+	}
+
+	return runtime.Nil, nil
+}
+
+// printf and printfln print their arguments formatted with the string:format directive language.
+func builtinPrintf(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 || context.Args[0].Type != runtime.StringValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "expected a format specifier")
+	}
+
+	s, err := strings.Format(context.Args[0].Str, context.Args[1:])
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
+	}
+
+	if context.Name == "printfln" {
+		s += "\n"
+	}
+
+	if err := runtime.CurrentOutput().Write(s); err != nil {
+		return runtime.NewIOErrorValue(err), nil
 	}
 
 	return runtime.Nil, nil
 }
 
 func builtinList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	l := runtime.NewListValue()
+	var l []*runtime.Value
 
 	for _, arg := range context.Args {
-		l.List = append(l.List, arg)
+		l = append(l, arg)
 	}
 
-	return l, nil
+	return runtime.NewListValueOf(l), nil
 }
 
 func builtinString(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -78,26 +137,13 @@ func builtinMath(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 		return nil, err
 	}
 
-	base := big.NewRat(0, 1)
-
-	var callback func(*big.Rat, *big.Rat) *big.Rat
-
-	switch context.Name {
-	case "+":
-		callback = base.Add
-	case "-":
-		callback = base.Sub
-	case "*":
-		callback = base.Mul
-	case "/":
-		if context.Args[1].Number.Cmp(base) == 0 {
-			return nil, runtime.NewRuntimeError(context.Pos, "division by zero")
-		}
+	result, err := runtime.NumberArithmetic(context.Name, context.Args[0], context.Args[1])
 
-		callback = base.Quo
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s", err.Error())
 	}
 
-	return runtime.NewNumberValueFromRat(callback(context.Args[0].Number, context.Args[1].Number)), nil
+	return result, nil
 }
 
 func builtinMathCmp(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -105,23 +151,22 @@ func builtinMathCmp(context *runtime.FunctionCallContext) (*runtime.Value, error
 		return nil, err
 	}
 
-	n1 := context.Args[0].Number
-	n2 := context.Args[1].Number
+	cmp, ordered := runtime.CompareNumbers(context.Args[0], context.Args[1])
 
 	ok := false
 
 	switch context.Name {
 	case ">":
-		ok = n1.Cmp(n2) == 1
+		ok = cmp == 1
 	case ">=":
-		ok = n1.Cmp(n2) >= 0
+		ok = cmp >= 0
 	case "<":
-		ok = n1.Cmp(n2) == -1
+		ok = cmp == -1
 	case "<=":
-		ok = n1.Cmp(n2) <= 0
+		ok = cmp <= 0
 	}
 
-	return runtime.BooleanValueFor(ok), nil
+	return runtime.BooleanValueFor(ordered && ok), nil
 }
 
 func builtinEquals(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -129,7 +174,7 @@ func builtinEquals(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, err
 	}
 
-	return runtime.BooleanValueFor(context.Args[0].Equals(context.Args[1])), nil
+	return runtime.BooleanValueFor(equals(context.Args[0], context.Args[1])), nil
 }
 
 func builtinNotEquals(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -137,7 +182,37 @@ func builtinNotEquals(context *runtime.FunctionCallContext) (*runtime.Value, err
 		return nil, err
 	}
 
-	return runtime.BooleanValueFor(!context.Args[0].Equals(context.Args[1])), nil
+	return runtime.BooleanValueFor(!equals(context.Args[0], context.Args[1])), nil
+}
+
+// builtinCompare orders any two values, see runtime.Compare.
+func builtinCompare(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(runtime.Compare(context.Args[0], context.Args[1]))), nil
+}
+
+// builtinHash returns the hash of a value as a non-negative integer. Values that are equal have the same hash.
+func builtinHash(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromBigInt(new(big.Int).SetUint64(context.Args[0].Hash())), nil
+}
+
+// equals compares numbers by value regardless of their exactness, so (= 1 1.0) holds.
+// Other values are compared with Value.Equals.
+func equals(a *runtime.Value, b *runtime.Value) bool {
+	if a.Type == runtime.NumberValue && b.Type == runtime.NumberValue {
+		cmp, ordered := runtime.CompareNumbers(a, b)
+
+		return ordered && cmp == 0
+	}
+
+	return a.Equals(b)
 }
 
 func builtinAnd(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -190,13 +265,13 @@ func builtinQuoted2List(context *runtime.FunctionCallContext) (*runtime.Value, e
 	quoted := context.Args[0].Quoted
 
 	if list, isList := quoted.(*parser.ListNode); isList {
-		l := runtime.NewListValue()
+		var l []*runtime.Value
 
 		for _, listNode := range list.Nodes {
-			l.List = append(l.List, runtime.NewQuotedValue(listNode))
+			l = append(l, runtime.NewQuotedValue(listNode))
 		}
 
-		return l, nil
+		return runtime.NewListValueOf(l), nil
 	} else {
 		return nil, runtime.NewRuntimeError(context.Pos, "expected a quoted list, not %s", quoted.Name())
 	}
@@ -281,3 +356,98 @@ func builtinAssert(context *runtime.FunctionCallContext) (*runtime.Value, error)
 
 	return runtime.Nil, nil
 }
+
+func builtinCharToInteger(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.CharValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(context.Args[0].Char)), nil
+}
+
+func builtinIntegerToChar(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	code, err := runtime.Int64Argument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if code < 0 || code > utf8.MaxRune || !utf8.ValidRune(rune(code)) {
+		return nil, runtime.NewRuntimeError(context.Pos, "%d is not a valid Unicode code point", code)
+	}
+
+	return runtime.NewCharValue(rune(code)), nil
+}
+
+func builtinStringToList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for _, r := range context.Args[0].Str {
+		l = append(l, runtime.NewCharValue(r))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+func builtinListToString(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+		return nil, err
+	}
+
+	runes := make([]rune, context.Args[0].List.Len())
+
+	for i, item := range context.Args[0].List.Values() {
+		if item.Type != runtime.CharValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "expected a list of chars, got %s at index %d", item.Type, i)
+		}
+
+		runes[i] = item.Char
+	}
+
+	return runtime.NewStringValue(string(runes)), nil
+}
+
+// builtinError creates an error value from a kind and a message, or only a message.
+func builtinError(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err == nil {
+		return runtime.NewErrorValue("error", context.Args[0].Str), nil
+	}
+
+	if err := runtime.ValidateArguments(context, runtime.KeywordValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewErrorValue(context.Args[0].Keyword, context.Args[1].Str), nil
+}
+
+func builtinIsError(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.ErrorValue), nil
+}
+
+func builtinErrorKind(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.ErrorValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewKeywordValue(context.Args[0].Keyword), nil
+}
+
+func builtinErrorMessage(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.ErrorValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewStringValue(context.Args[0].Str), nil
+}
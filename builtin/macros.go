@@ -18,6 +18,9 @@ var Macros = runtime.Mactab{
 	"case":      runtime.NewMacro(builtinCase, false),
 	"export":    runtime.NewMacro(builtinExport, false),
 	"namespace": runtime.NewMacro(builtinNamespace, true, "identifier"),
+	"lazy-seq":  runtime.NewMacro(builtinLazySeq, true, "any"),
+
+	"with-output-to-string": runtime.NewMacro(builtinWithOutputToString, false),
 }
 
 func builtinDefmacro(context *runtime.MacroCallContext) (*runtime.Value, error) {
@@ -156,8 +159,8 @@ func builtinFor(context *runtime.MacroCallContext) (*runtime.Value, error) {
 		return nil, err
 	}
 
-	if l.Type != runtime.ListValue {
-		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list to iterate over")
+	if !runtime.IsIterable(l) {
+		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list, vector or lazy sequence to iterate over")
 	}
 
 	var args []string
@@ -178,7 +181,10 @@ func builtinFor(context *runtime.MacroCallContext) (*runtime.Value, error) {
 
 	callbackBlock := runtime.NewBlock([]parser.Node{context.Nodes[2]}, runtime.NewScope(context.Block.Scope))
 
-	for i, item := range l.List {
+	// lazy sequences are realized one item at a time
+	i := 0
+
+	err = runtime.Iterate(l, func(item *runtime.Value) (bool, error) {
 		if len(args) >= 1 {
 			callbackBlock.Scope.SetSymbol(args[0], runtime.NewSymbol(item))
 		}
@@ -187,16 +193,54 @@ func builtinFor(context *runtime.MacroCallContext) (*runtime.Value, error) {
 			callbackBlock.Scope.SetSymbol(args[1], runtime.NewSymbol(runtime.NewNumberValueFromInt64(int64(i))))
 		}
 
+		i++
+
 		_, err := callbackBlock.Eval()
 
-		if err != nil {
-			return nil, err
-		}
+		return true, err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	return runtime.Nil, nil
 }
 
+// builtinLazySeq returns a lazy sequence of which the body is evaluated when it's first realized.
+// The body should return a list, a lazy sequence or nil, which is an empty sequence.
+// This makes recursive sequences possible, like (defun nats (n) (lazy:cons n (lazy-seq (nats (+ n 1))))).
+func builtinLazySeq(context *runtime.MacroCallContext) (*runtime.Value, error) {
+	body := context.Nodes[0]
+
+	return runtime.NewSeqValue(runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		result, err := context.Block.EvalNode(body)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if result.Type == runtime.NilValue {
+			return nil, nil, nil
+		}
+
+		if !runtime.IsIterable(result) {
+			return nil, nil, runtime.NewRuntimeError(body.Pos(), "expected a list, lazy sequence or nil, got %s", result.Type)
+		}
+
+		seq := runtime.ToSeq(result)
+		first, ok, err := seq.First()
+
+		if err != nil || !ok {
+			return nil, nil, err
+		}
+
+		rest, err := seq.Rest()
+
+		return first, rest, err
+	})), nil
+}
+
 func builtinWhile(context *runtime.MacroCallContext) (*runtime.Value, error) {
 recheck:
 	callback, err := context.Block.EvalNode(context.Nodes[0])
@@ -367,3 +411,22 @@ func builtinNamespace(context *runtime.MacroCallContext) (*runtime.Value, error)
 
 	return runtime.Nil, nil
 }
+
+// builtinWithOutputToString evaluates its body with print and println writing to a string, which it returns.
+func builtinWithOutputToString(context *runtime.MacroCallContext) (*runtime.Value, error) {
+	s, err := runtime.WithOutputToString(func() error {
+		for _, node := range context.Nodes {
+			if _, err := context.Block.EvalNode(node); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewStringValue(s), nil
+}
@@ -0,0 +1,139 @@
+package builtin
+
+import "github.com/raoulvdberge/risp/runtime"
+
+// portArguments validates arguments of the given types, optionally followed by a port.
+// It returns that port, or def if it's omitted.
+func portArguments(context *runtime.FunctionCallContext, def *runtime.Port, types ...runtime.ValueType) (*runtime.Port, error) {
+	if len(context.Args) == len(types)+1 {
+		if err := runtime.ValidateArguments(context, append(types, runtime.PortValue)...); err != nil {
+			return nil, err
+		}
+
+		return context.Args[len(types)].Port, nil
+	}
+
+	if err := runtime.ValidateArguments(context, types...); err != nil {
+		return nil, err
+	}
+
+	return def, nil
+}
+
+func inputPortArgument(context *runtime.FunctionCallContext) (*runtime.Port, error) {
+	port, err := portArguments(context, runtime.Stdin)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !port.IsInput() {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s is not an input port", context.Name, port.Name)
+	}
+
+	return port, nil
+}
+
+// builtinReadLine reads a line from a port, stdin by default. It returns nil at the end of the port.
+func builtinReadLine(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	port, err := inputPortArgument(context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	line, ok, err := port.ReadLine()
+
+	if err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	if !ok {
+		return runtime.Nil, nil
+	}
+
+	return runtime.NewStringValue(line), nil
+}
+
+// builtinReadChar reads a char from a port, stdin by default. It returns nil at the end of the port.
+func builtinReadChar(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	port, err := inputPortArgument(context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok, err := port.ReadChar()
+
+	if err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	if !ok {
+		return runtime.Nil, nil
+	}
+
+	return runtime.NewCharValue(r), nil
+}
+
+// builtinReadAll reads the rest of a port, stdin by default, as a string. Use read-forms to read it as forms.
+func builtinReadAll(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	port, err := inputPortArgument(context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := port.ReadAll()
+
+	if err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.NewStringValue(data), nil
+}
+
+// builtinWrite writes a value like print does, to the given port or the current output.
+func builtinWrite(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	port, err := portArguments(context, runtime.CurrentOutput(), runtime.AnyValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !port.IsOutput() {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s is not an output port", context.Name, port.Name)
+	}
+
+	if err := port.Write(context.Args[0].String()); err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func builtinFlush(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	port, err := portArguments(context, runtime.CurrentOutput())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := port.Flush(); err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func builtinClose(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.PortValue); err != nil {
+		return nil, err
+	}
+
+	if err := context.Args[0].Port.Close(); err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
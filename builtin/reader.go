@@ -0,0 +1,173 @@
+package builtin
+
+import (
+	"errors"
+	"github.com/raoulvdberge/risp/lexer"
+	"github.com/raoulvdberge/risp/parser"
+	"github.com/raoulvdberge/risp/runtime"
+)
+
+// readerArguments returns the source of the reader functions, which is omitted for read, and whether forms
+// are returned as quoted values. That's the case if the last argument is :quoted instead of :data.
+func readerArguments(context *runtime.FunctionCallContext) (*runtime.Value, bool, error) {
+	args := context.Args
+	quoted := false
+
+	if len(args) > 0 && args[len(args)-1].Type == runtime.KeywordValue {
+		switch args[len(args)-1].Keyword {
+		case "quoted":
+			quoted = true
+		case "data":
+		default:
+			return nil, false, runtime.NewRuntimeError(context.Pos, "%s: unknown mode %s, expected :data or :quoted", context.Name, args[len(args)-1])
+		}
+
+		args = args[:len(args)-1]
+	}
+
+	if len(args) > 1 {
+		return nil, false, runtime.NewRuntimeError(context.Pos, "%s: too many arguments", context.Name)
+	}
+
+	if len(args) == 0 {
+		return nil, quoted, nil
+	}
+
+	return args[0], quoted, nil
+}
+
+// readerError converts an error of the reader into an error value, of kind :syntax for syntax errors.
+func readerError(err error) *runtime.Value {
+	var syntaxErr *lexer.SyntaxError
+
+	if errors.As(err, &syntaxErr) {
+		return runtime.NewSyntaxErrorValue(syntaxErr)
+	}
+
+	return runtime.NewIOErrorValue(err)
+}
+
+func readerResult(node parser.Node, quoted bool) (*runtime.Value, error) {
+	if quoted {
+		return runtime.NewQuotedValue(node), nil
+	}
+
+	return runtime.NodeToData(node)
+}
+
+// builtinReadString reads the first form of a string. It returns nil if there is none.
+func builtinReadString(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	source, quoted, err := readerArguments(context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if source == nil || source.Type != runtime.StringValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a string", context.Name)
+	}
+
+	forms, err := runtime.ParseForms("<string>", source.Str)
+
+	if err != nil {
+		return readerError(err), nil
+	}
+
+	if len(forms) == 0 {
+		return runtime.Nil, nil
+	}
+
+	value, err := readerResult(forms[0], quoted)
+
+	if err != nil {
+		return readerError(err), nil
+	}
+
+	return value, nil
+}
+
+// builtinRead reads the next form from a port, stdin by default. It returns nil at the end of the port.
+func builtinRead(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	source, quoted, err := readerArguments(context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	port := runtime.Stdin
+
+	if source != nil {
+		if source.Type != runtime.PortValue || !source.Port.IsInput() {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected an input port", context.Name)
+		}
+
+		port = source.Port
+	}
+
+	form, ok, err := port.ReadForm()
+
+	if err != nil {
+		return readerError(err), nil
+	}
+
+	if !ok {
+		return runtime.Nil, nil
+	}
+
+	value, err := readerResult(form, quoted)
+
+	if err != nil {
+		return readerError(err), nil
+	}
+
+	return value, nil
+}
+
+// builtinReadForms reads all forms of a string or the rest of a port into a list. It's the reader's read-all,
+// but that name is taken by the port function that reads the rest of a port as a string.
+func builtinReadForms(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	source, quoted, err := readerArguments(context)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var forms []parser.Node
+
+	switch {
+	case source != nil && source.Type == runtime.StringValue:
+		forms, err = runtime.ParseForms("<string>", source.Str)
+	case source != nil && source.Type == runtime.PortValue && source.Port.IsInput():
+		for {
+			form, ok, readErr := source.Port.ReadForm()
+
+			if readErr != nil || !ok {
+				err = readErr
+
+				break
+			}
+
+			forms = append(forms, form)
+		}
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a string or an input port", context.Name)
+	}
+
+	if err != nil {
+		return readerError(err), nil
+	}
+
+	var l []*runtime.Value
+
+	for _, form := range forms {
+		value, err := readerResult(form, quoted)
+
+		if err != nil {
+			return readerError(err), nil
+		}
+
+		l = append(l, value)
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
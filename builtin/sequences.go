@@ -0,0 +1,87 @@
+package builtin
+
+import "github.com/raoulvdberge/risp/runtime"
+
+// Lists are linked lists, so cons and cdr share the cells of the list they're given and take constant time,
+// see runtime.List. Vectors are persistent too: cdr skips the first item in constant time while cons
+// builds a new vector, see runtime.Vector.
+
+func builtinVector(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	return runtime.NewVectorValue(runtime.NewVector(context.Args)), nil
+}
+
+func builtinIsVector(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.VectorValue), nil
+}
+
+func builtinListToVector(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewVectorValue(runtime.NewVector(context.Args[0].List.Values())), nil
+}
+
+func builtinVectorToList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.VectorValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewListValueOf(context.Args[0].Vector.Values()), nil
+}
+
+// builtinCons returns a list or vector with an item in front, (cons x nil) returns a list of x.
+func builtinCons(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	rest := context.Args[1]
+
+	switch rest.Type {
+	case runtime.NilValue:
+		return runtime.NewListValueOf([]*runtime.Value{context.Args[0]}), nil
+	case runtime.ListValue:
+		return runtime.NewListValueFrom(rest.List.Cons(context.Args[0])), nil
+	case runtime.VectorValue:
+		return runtime.NewVectorValue(runtime.NewVector(append([]*runtime.Value{context.Args[0]}, rest.Vector.Values()...))), nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: argument 2 should be a list, a vector or nil, got %s", context.Name, rest.Type)
+	}
+}
+
+// builtinCar returns the first item of a non-empty list or vector.
+func builtinCar(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	if runtime.SequenceLen(context.Args[0]) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: empty %s", context.Name, context.Args[0].Type)
+	}
+
+	return runtime.SequenceGet(context.Args[0], 0), nil
+}
+
+// builtinCdr returns a non-empty list or vector without its first item.
+func builtinCdr(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	l := context.Args[0]
+
+	if runtime.SequenceLen(l) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: empty %s", context.Name, l.Type)
+	}
+
+	if l.Type == runtime.VectorValue {
+		return runtime.NewVectorValue(l.Vector.Drop(1)), nil
+	}
+
+	return runtime.NewListValueFrom(l.List.Rest()), nil
+}
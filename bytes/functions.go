@@ -0,0 +1,463 @@
+package bytes
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/raoulvdberge/risp/runtime"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Bytes are immutable, functions that change bytes return new bytes. Bytes are written as #x"00ff", and displayed as a hex dump.
+// Integers are read and written with a width of 1, 2, 4 or 8 bytes and a byte order of :big or :little.
+var Symbols = runtime.Symtab{
+	"bytes?":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesIsBytes, "bytes?"))),
+	"new":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesNew, "new"))),
+	"length":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesLength, "length"))),
+	"get":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesGet, "get"))),
+	"set":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesSet, "set"))),
+	"slice":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesSlice, "slice"))),
+	"concat":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesConcat, "concat"))),
+	"from-list":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesFromList, "from-list"))),
+	"to-list":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesToList, "to-list"))),
+	"from-string": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesFromString, "from-string"))),
+	"to-string":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesToString, "to-string"))),
+	"read-int":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesReadInt, "read-int"))),
+	"read-uint":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesReadInt, "read-uint"))),
+	"write-int":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesWriteInt, "write-int"))),
+	"write-uint":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(bytesWriteInt, "write-uint"))),
+}
+
+// encodings are the text encodings to convert strings from and to, besides :utf-8 and :ascii.
+var encodings = map[string]encoding.Encoding{
+	"latin-1":      charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+}
+
+func bytesIsBytes(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.BytesValue), nil
+}
+
+// byteArgument returns argument i, which should be an integer from 0 to 255.
+func byteArgument(context *runtime.FunctionCallContext, i int) (byte, error) {
+	n, err := runtime.Int64Argument(context, i)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 0 || n > 255 {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be a byte from 0 to 255, got %d", context.Name, i+1, n)
+	}
+
+	return byte(n), nil
+}
+
+// indexArgument returns argument i as an index in data, which may be the length of data if end is set.
+func indexArgument(context *runtime.FunctionCallContext, i int, data []byte, end bool) (int, error) {
+	n, err := runtime.Int64Argument(context, i)
+
+	if err != nil {
+		return 0, err
+	}
+
+	max := int64(len(data))
+
+	if !end {
+		max--
+	}
+
+	if n < 0 || n > max {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: index %d out of range for %d bytes", context.Name, n, len(data))
+	}
+
+	return int(n), nil
+}
+
+// bytesNew returns n zero bytes, or n copies of a byte.
+func bytesNew(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	fill := byte(0)
+
+	if len(context.Args) == 2 {
+		if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+
+		b, err := byteArgument(context, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fill = b
+	} else if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	n, err := runtime.Int64Argument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: length can't be negative, got %d", context.Name, n)
+	}
+
+	data := make([]byte, n)
+
+	for i := range data {
+		data[i] = fill
+	}
+
+	return runtime.NewBytesValue(data), nil
+}
+
+func bytesLength(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(len(context.Args[0].Bytes))), nil
+}
+
+// bytesGet returns the byte at an index as an integer.
+func bytesGet(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	data := context.Args[0].Bytes
+
+	i, err := indexArgument(context, 1, data, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(data[i])), nil
+}
+
+// bytesSet returns a copy of bytes with the byte at an index replaced.
+func bytesSet(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	data := context.Args[0].Bytes
+
+	i, err := indexArgument(context, 1, data, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := byteArgument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := append([]byte{}, data...)
+	result[i] = b
+
+	return runtime.NewBytesValue(result), nil
+}
+
+// bytesSlice returns the bytes from start up to end, or up to the end if it's omitted.
+func bytesSlice(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) == 2 {
+		if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+	} else if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	data := context.Args[0].Bytes
+
+	start, err := indexArgument(context, 1, data, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(data)
+
+	if len(context.Args) == 3 {
+		end, err = indexArgument(context, 2, data, true)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if start > end {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: start can't be after end (%d > %d)", context.Name, start, end)
+	}
+
+	return runtime.NewBytesValue(data[start:end:end]), nil
+}
+
+// bytesConcat joins any number of bytes.
+func bytesConcat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	var result []byte
+
+	for i, arg := range context.Args {
+		if arg.Type != runtime.BytesValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be of type %s, got %s", context.Name, i+1, runtime.BytesValue, arg.Type)
+		}
+
+		result = append(result, arg.Bytes...)
+	}
+
+	return runtime.NewBytesValue(result), nil
+}
+
+// bytesFromList converts a list of integers from 0 to 255 to bytes.
+func bytesFromList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, context.Args[0].List.Len())
+
+	for i, item := range context.Args[0].List.Values() {
+		if item.Type != runtime.NumberValue || !item.NumberIsInteger() || item.NumberToBigInt().Cmp(big.NewInt(255)) > 0 || item.NumberToBigInt().Sign() < 0 {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a list of bytes from 0 to 255, got %s", context.Name, item)
+		}
+
+		data[i] = byte(item.NumberToInt64())
+	}
+
+	return runtime.NewBytesValue(data), nil
+}
+
+func bytesToList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue); err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for _, b := range context.Args[0].Bytes {
+		l = append(l, runtime.NewNumberValueFromInt64(int64(b)))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// encodingArgument checks argument i is one of the supported encodings: :utf-8, :ascii, :latin-1, :windows-1252, :utf-16le or :utf-16be.
+func encodingArgument(context *runtime.FunctionCallContext, i int) (string, error) {
+	name := context.Args[i].Keyword
+
+	if _, ok := encodings[name]; ok || name == "utf-8" || name == "ascii" {
+		return name, nil
+	}
+
+	return "", runtime.NewRuntimeError(context.Pos, "%s: unknown encoding %s", context.Name, context.Args[i])
+}
+
+// bytesFromString encodes a string with an encoding. A string with characters the encoding
+// can't represent returns an error of kind :encoding.
+func bytesFromString(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	name, err := encodingArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := context.Args[0].Str
+
+	switch name {
+	case "utf-8":
+		return runtime.NewBytesValue([]byte(s)), nil
+	case "ascii":
+		for _, r := range s {
+			if r >= utf8.RuneSelf {
+				return runtime.NewErrorValue("encoding", fmt.Sprintf("character '%c' can't be encoded as ascii", r)), nil
+			}
+		}
+
+		return runtime.NewBytesValue([]byte(s)), nil
+	}
+
+	data, err := encodings[name].NewEncoder().Bytes([]byte(s))
+
+	if err != nil {
+		return runtime.NewErrorValue("encoding", err.Error()), nil
+	}
+
+	return runtime.NewBytesValue(data), nil
+}
+
+// bytesToString decodes bytes with an encoding. Bytes that aren't valid in the encoding return an error of kind :encoding.
+func bytesToString(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	name, err := encodingArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := context.Args[0].Bytes
+
+	switch name {
+	case "utf-8", "ascii":
+		for i, b := range data {
+			if name == "ascii" && b >= utf8.RuneSelf {
+				return runtime.NewErrorValue("encoding", fmt.Sprintf("byte %d at offset %d is not ascii", b, i)), nil
+			}
+		}
+
+		if !utf8.Valid(data) {
+			return runtime.NewErrorValue("encoding", "bytes are not valid UTF-8"), nil
+		}
+
+		return runtime.NewStringValue(string(data)), nil
+	}
+
+	decoded, err := encodings[name].NewDecoder().Bytes(data)
+
+	if err != nil {
+		return runtime.NewErrorValue("encoding", err.Error()), nil
+	}
+
+	return runtime.NewStringValue(string(decoded)), nil
+}
+
+// integerArguments returns the offset, width and byte order of the integer functions, from argument 1 onwards.
+func integerArguments(context *runtime.FunctionCallContext, data []byte) (int, int, binary.ByteOrder, error) {
+	offset, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	width, err := runtime.Int64Argument(context, 2)
+
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if width != 1 && width != 2 && width != 4 && width != 8 {
+		return 0, 0, nil, runtime.NewRuntimeError(context.Pos, "%s: width should be 1, 2, 4 or 8, got %d", context.Name, width)
+	}
+
+	if offset < 0 || offset > int64(len(data))-width {
+		return 0, 0, nil, runtime.NewRuntimeError(context.Pos, "%s: %d bytes at offset %d out of range for %d bytes", context.Name, width, offset, len(data))
+	}
+
+	var order binary.ByteOrder
+
+	switch context.Args[3].Keyword {
+	case "big":
+		order = binary.BigEndian
+	case "little":
+		order = binary.LittleEndian
+	default:
+		return 0, 0, nil, runtime.NewRuntimeError(context.Pos, "%s: byte order should be :big or :little, got %s", context.Name, context.Args[3])
+	}
+
+	return int(offset), int(width), order, nil
+}
+
+// bytesReadInt reads an integer at an offset, like (bytes:read-uint data 0 4 :big). read-int reads it in two's complement.
+func bytesReadInt(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.NumberValue, runtime.NumberValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	data := context.Args[0].Bytes
+
+	offset, width, order, err := integerArguments(context, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, 8)
+
+	// widen the integer to 8 bytes, so it can be read with Uint64
+	if order == binary.BigEndian {
+		copy(buffer[8-width:], data[offset:offset+width])
+	} else {
+		copy(buffer, data[offset:offset+width])
+	}
+
+	n := order.Uint64(buffer)
+
+	if context.Name == "read-int" {
+		shift := uint(64 - 8*width)
+
+		return runtime.NewNumberValueFromInt64(int64(n<<shift) >> shift), nil
+	}
+
+	return runtime.NewNumberValueFromBigInt(new(big.Int).SetUint64(n)), nil
+}
+
+// bytesWriteInt returns a copy of bytes with an integer written at an offset, like (bytes:write-uint data 0 4 :big 80).
+// write-int writes it in two's complement.
+func bytesWriteInt(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.BytesValue, runtime.NumberValue, runtime.NumberValue, runtime.KeywordValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	data := context.Args[0].Bytes
+
+	offset, width, order, err := integerArguments(context, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := runtime.IntegerArgument(context, 4)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bits := uint(8 * width)
+	min, max := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), bits)
+
+	if context.Name == "write-int" {
+		min = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), bits-1))
+		max = new(big.Int).Lsh(big.NewInt(1), bits-1)
+	}
+
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s doesn't fit in %d bytes", context.Name, n, width)
+	}
+
+	// the lowest 64 bits, in two's complement for negative integers
+	u := new(big.Int).And(n, new(big.Int).SetUint64(1<<64-1)).Uint64()
+
+	buffer := make([]byte, 8)
+	order.PutUint64(buffer, u)
+
+	result := append([]byte{}, data...)
+
+	if order == binary.BigEndian {
+		copy(result[offset:], buffer[8-width:])
+	} else {
+		copy(result[offset:], buffer[:width])
+	}
+
+	return runtime.NewBytesValue(result), nil
+}
@@ -0,0 +1,155 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/raoulvdberge/risp/runtime"
+	"hash"
+	"hash/crc32"
+)
+
+// The functions in this namespace take strings, which are hashed as UTF-8, or bytes.
+// Digests are returned as lowercase hex strings, or as bytes if the last argument is :bytes instead of :hex.
+var Symbols = runtime.Symtab{
+	"md5":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoDigest, "md5"))),
+	"sha1":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoDigest, "sha1"))),
+	"sha256": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoDigest, "sha256"))),
+	"sha512": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoDigest, "sha512"))),
+	"hmac":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoHmac, "hmac"))),
+	"crc32":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoCrc32, "crc32"))),
+	"equal?": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(cryptoEqual, "equal?"))),
+}
+
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// digestArguments returns the arguments after checking there are n of them, optionally followed
+// by the output mode. It returns whether the digest should be returned as bytes.
+func digestArguments(context *runtime.FunctionCallContext, n int) ([]*runtime.Value, bool, error) {
+	args := context.Args
+	raw := false
+
+	if len(args) == n+1 && args[n].Type == runtime.KeywordValue {
+		switch args[n].Keyword {
+		case "bytes":
+			raw = true
+		case "hex":
+		default:
+			return nil, false, runtime.NewRuntimeError(context.Pos, "%s: unknown mode %s, expected :hex or :bytes", context.Name, args[n])
+		}
+
+		args = args[:n]
+	}
+
+	if len(args) != n {
+		return nil, false, runtime.NewRuntimeError(context.Pos, "%s: expected %d arguments, got %d", context.Name, n, len(args))
+	}
+
+	return args, raw, nil
+}
+
+func digestResult(digest []byte, raw bool) *runtime.Value {
+	if raw {
+		return runtime.NewBytesValue(digest)
+	}
+
+	return runtime.NewStringValue(hex.EncodeToString(digest))
+}
+
+// cryptoDigest hashes a string or bytes with the algorithm the function is named after.
+func cryptoDigest(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	_, raw, err := digestArguments(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := runtime.BytesArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	h := hashes[context.Name]()
+	h.Write(data)
+
+	return digestResult(h.Sum(nil), raw), nil
+}
+
+// cryptoHmac signs a message with a key, using the hash algorithm given as keyword, like (crypto:hmac :sha256 key message).
+func cryptoHmac(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	args, raw, err := digestArguments(context, 3)
+
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, ok := hashes[args[0].Keyword]
+
+	if args[0].Type != runtime.KeywordValue || !ok {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown hash algorithm %s, expected :md5, :sha1, :sha256 or :sha512", context.Name, args[0])
+	}
+
+	key, err := runtime.BytesArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := runtime.BytesArgument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	h := hmac.New(algorithm, key)
+	h.Write(message)
+
+	return digestResult(h.Sum(nil), raw), nil
+}
+
+// cryptoCrc32 returns the IEEE CRC-32 checksum of a string or bytes as an integer.
+func cryptoCrc32(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	data, err := runtime.BytesArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(crc32.ChecksumIEEE(data))), nil
+}
+
+// cryptoEqual compares two strings or bytes in constant time, so comparing a secret like a signature
+// doesn't reveal how much of it matched.
+func cryptoEqual(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	a, err := runtime.BytesArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := runtime.BytesArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(subtle.ConstantTimeCompare(a, b) == 1), nil
+}
@@ -0,0 +1,272 @@
+package csv
+
+import (
+	"encoding/csv"
+	"github.com/raoulvdberge/risp/runtime"
+	"io"
+	"strings"
+)
+
+// The functions in this namespace accept the options :delimiter (a char, #\, by default), and for reading
+// :comment (a char that starts comment lines), :lazy-quotes (allows quotes in unquoted fields) and :header
+// (uses the first row as keys, every following row is returned as a keyword list). Writing accepts :crlf
+// to end lines with \r\n. Use #\tab as delimiter for TSV.
+var Symbols = runtime.Symtab{
+	"read":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(csvRead, "read"))),
+	"each":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(csvEach, "each"))),
+	"write": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(csvWrite, "write"))),
+}
+
+type options struct {
+	delimiter  rune
+	comment    rune
+	lazyQuotes bool
+	header     bool
+	crlf       bool
+}
+
+func parseOptions(context *runtime.FunctionCallContext, args []*runtime.Value, writing bool) (*options, error) {
+	if len(args)%2 != 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected options as keyword and value pairs", context.Name)
+	}
+
+	o := &options{delimiter: ','}
+
+	for i := 0; i < len(args); i += 2 {
+		key, value := args[i], args[i+1]
+
+		if key.Type != runtime.KeywordValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a keyword, got %s", context.Name, key.Type)
+		}
+
+		switch {
+		case key.Keyword == "delimiter" || (key.Keyword == "comment" && !writing):
+			if value.Type != runtime.CharValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a char", context.Name, key)
+			}
+
+			if key.Keyword == "delimiter" {
+				o.delimiter = value.Char
+			} else {
+				o.comment = value.Char
+			}
+		case key.Keyword == "crlf" && writing, (key.Keyword == "lazy-quotes" || key.Keyword == "header") && !writing:
+			if value.Type != runtime.BooleanValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a boolean", context.Name, key)
+			}
+
+			switch key.Keyword {
+			case "crlf":
+				o.crlf = value.Boolean
+			case "lazy-quotes":
+				o.lazyQuotes = value.Boolean
+			default:
+				o.header = value.Boolean
+			}
+		default:
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown option %s", context.Name, key)
+		}
+	}
+
+	return o, nil
+}
+
+// newReader returns a CSV reader for a string or an input port.
+func newReader(context *runtime.FunctionCallContext, source *runtime.Value, o *options) (*csv.Reader, error) {
+	var r io.Reader
+
+	switch {
+	case source.Type == runtime.StringValue:
+		r = strings.NewReader(source.Str)
+	case source.Type == runtime.PortValue && source.Port.IsInput():
+		r = source.Port.Reader()
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a string or an input port, got %s", context.Name, source.Type)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = o.delimiter
+	reader.Comment = o.comment
+	reader.LazyQuotes = o.lazyQuotes
+	reader.ReuseRecord = true
+
+	return reader, nil
+}
+
+// rowReader reads rows as lists of strings, or as keyword lists if the header option is set.
+type rowReader struct {
+	reader *csv.Reader
+	header []string
+	o      *options
+}
+
+// next returns the next row, or nil at the end of the input.
+func (r *rowReader) next() (*runtime.Value, error) {
+	record, err := r.reader.Read()
+
+	if err == io.EOF {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.o.header && r.header == nil {
+		r.header = append([]string{}, record...)
+
+		return r.next()
+	}
+
+	var row []*runtime.Value
+
+	for i, field := range record {
+		if r.header != nil {
+			row = append(row, runtime.NewKeywordValue(r.header[i]))
+		}
+
+		row = append(row, runtime.NewStringValue(field))
+	}
+
+	return runtime.NewListValueOf(row), nil
+}
+
+func csvRead(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a string or an input port", context.Name)
+	}
+
+	o, err := parseOptions(context, context.Args[1:], false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := newReader(context, context.Args[0], o)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &rowReader{reader: reader, o: o}
+	var l []*runtime.Value
+
+	for {
+		row, err := rows.next()
+
+		if err != nil {
+			return runtime.NewErrorValue("csv", err.Error()), nil
+		}
+
+		if row == nil {
+			return runtime.NewListValueOf(l), nil
+		}
+
+		l = append(l, row)
+	}
+}
+
+// csvEach reads one row at a time and calls a function with every row, so large inputs don't have to be held in memory.
+func csvEach(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 2 || context.Args[1].Type != runtime.FunctionValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a string or an input port and a function", context.Name)
+	}
+
+	o, err := parseOptions(context, context.Args[2:], false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := newReader(context, context.Args[0], o)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &rowReader{reader: reader, o: o}
+
+	for {
+		row, err := rows.next()
+
+		if err != nil {
+			return runtime.NewErrorValue("csv", err.Error()), nil
+		}
+
+		if row == nil {
+			return runtime.Nil, nil
+		}
+
+		if _, err := context.Args[1].Function.Call(context.Block, []*runtime.Value{row}, context.Pos); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// csvWrite writes a list of rows, quoting fields where needed. Fields are written like print does, nil as an empty field.
+// It returns a string, or writes to an output port if one is given after the rows.
+func csvWrite(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 || context.Args[0].Type != runtime.ListValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a list of rows", context.Name)
+	}
+
+	args := context.Args[1:]
+
+	var port *runtime.Port
+
+	if len(args) > 0 && args[0].Type == runtime.PortValue {
+		if !args[0].Port.IsOutput() {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: %s is not an output port", context.Name, args[0].Port.Name)
+		}
+
+		port = args[0].Port
+		args = args[1:]
+	}
+
+	o, err := parseOptions(context, args, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+
+	writer := csv.NewWriter(&out)
+	writer.Comma = o.delimiter
+	writer.UseCRLF = o.crlf
+
+	for _, row := range context.Args[0].List.Values() {
+		if row.Type != runtime.ListValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a list of rows, got a %s row", context.Name, row.Type)
+		}
+
+		fields := row.List.Values()
+		record := make([]string, len(fields))
+
+		for i, field := range fields {
+			if field.Type != runtime.NilValue {
+				record[i] = field.String()
+			}
+		}
+
+		if err := writer.Write(record); err != nil {
+			return runtime.NewErrorValue("csv", err.Error()), nil
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return runtime.NewErrorValue("csv", err.Error()), nil
+	}
+
+	if port != nil {
+		if err := port.Write(out.String()); err != nil {
+			return runtime.NewIOErrorValue(err), nil
+		}
+
+		return runtime.Nil, nil
+	}
+
+	return runtime.NewStringValue(out.String()), nil
+}
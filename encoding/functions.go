@@ -0,0 +1,107 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/raoulvdberge/risp/runtime"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// The encode functions take strings, which are encoded as UTF-8, or bytes. The decode functions return bytes,
+// or a string if the last argument is :string instead of :bytes. Invalid input returns an error of kind :encoding.
+var Symbols = runtime.Symtab{
+	"base64-encode":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingEncode, "base64-encode"))),
+	"base64-decode":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingDecode, "base64-decode"))),
+	"base64url-encode": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingEncode, "base64url-encode"))),
+	"base64url-decode": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingDecode, "base64url-decode"))),
+	"hex-encode":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingEncode, "hex-encode"))),
+	"hex-decode":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingDecode, "hex-decode"))),
+	"url-encode":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingEncode, "url-encode"))),
+	"url-decode":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(encodingDecode, "url-decode"))),
+}
+
+// encodingEncode encodes a string or bytes. base64url uses the URL-safe alphabet without padding, like JWTs do,
+// url-encode escapes a string to be used in a URL query.
+func encodingEncode(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	data, err := runtime.BytesArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var s string
+
+	switch context.Name {
+	case "base64-encode":
+		s = base64.StdEncoding.EncodeToString(data)
+	case "base64url-encode":
+		s = base64.RawURLEncoding.EncodeToString(data)
+	case "hex-encode":
+		s = hex.EncodeToString(data)
+	case "url-encode":
+		s = url.QueryEscape(string(data))
+	}
+
+	return runtime.NewStringValue(s), nil
+}
+
+// encodingDecode decodes a string. base64url-decode accepts input with or without padding,
+// url-decode also turns + into a space.
+func encodingDecode(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	asString := false
+
+	if len(context.Args) == 2 {
+		if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.KeywordValue); err != nil {
+			return nil, err
+		}
+
+		switch context.Args[1].Keyword {
+		case "string":
+			asString = true
+		case "bytes":
+		default:
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown mode %s, expected :bytes or :string", context.Name, context.Args[1])
+		}
+	} else if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	s := context.Args[0].Str
+
+	var data []byte
+	var err error
+
+	switch context.Name {
+	case "base64-decode":
+		data, err = base64.StdEncoding.DecodeString(s)
+	case "base64url-decode":
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	case "hex-decode":
+		data, err = hex.DecodeString(s)
+	case "url-decode":
+		var decoded string
+
+		decoded, err = url.QueryUnescape(s)
+		data = []byte(decoded)
+	}
+
+	if err != nil {
+		return runtime.NewErrorValue("encoding", err.Error()), nil
+	}
+
+	if asString {
+		if !utf8.Valid(data) {
+			return runtime.NewErrorValue("encoding", "decoded data is not valid UTF-8"), nil
+		}
+
+		return runtime.NewStringValue(string(data)), nil
+	}
+
+	return runtime.NewBytesValue(data), nil
+}
@@ -0,0 +1,389 @@
+package fs
+
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Functions in this namespace return an error value when the file system operation fails,
+// so scripts can handle missing files and the like. Relative paths are resolved against the
+// directory of the running script.
+var Symbols = runtime.Symtab{
+	"read":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsRead, "read"))),
+	"write":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsWrite, "write"))),
+	"append":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsWrite, "append"))),
+	"lines":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsLines, "lines"))),
+	"open":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsOpen, "open"))),
+	"list":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsList, "list"))),
+	"glob":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsGlob, "glob"))),
+	"stat":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsStat, "stat"))),
+	"mkdir":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsMkdir, "mkdir"))),
+	"mkdir-all":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsMkdir, "mkdir-all"))),
+	"remove":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsRemove, "remove"))),
+	"remove-all": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsRemove, "remove-all"))),
+	"rename":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsRename, "rename"))),
+	"temp-file":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsTemp, "temp-file"))),
+	"temp-dir":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsTemp, "temp-dir"))),
+	"exists?":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsExists, "exists?"))),
+	"file?":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsExists, "file?"))),
+	"dir?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(fsExists, "dir?"))),
+}
+
+func fsRead(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.NewStringValue(string(data)), nil
+}
+
+// fsWrite handles write, which replaces the contents of a file, and append.
+// Both create the file if it doesn't exist.
+func fsWrite(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	if context.Name == "append" {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0666)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	_, err = file.WriteString(context.Args[1].Str)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func fsLines(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	port, err := fsOpen(context)
+
+	if err != nil || port.Type != runtime.PortValue {
+		return port, err
+	}
+
+	defer port.Port.Close()
+
+	var lines []*runtime.Value
+
+	for {
+		line, ok, err := port.Port.ReadLine()
+
+		if err != nil {
+			return ErrorValue(err), nil
+		}
+
+		if !ok {
+			return runtime.NewListValueOf(lines), nil
+		}
+
+		lines = append(lines, runtime.NewStringValue(line))
+	}
+}
+
+// fsOpen opens a file and returns a port. The optional mode is :read (the default), :write or :append.
+func fsOpen(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	mode := "read"
+
+	if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.KeywordValue); err == nil {
+		mode = context.Args[1].Keyword
+	} else if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	var file *os.File
+
+	switch mode {
+	case "read":
+		file, err = os.Open(path)
+	case "write":
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	case "append":
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown mode :%s, expected :read, :write or :append", context.Name, mode)
+	}
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	if mode == "read" {
+		return runtime.NewPortValue(runtime.NewInputPort(context.Args[0].Str, file)), nil
+	}
+
+	return runtime.NewPortValue(runtime.NewBufferedOutputPort(context.Args[0].Str, file)), nil
+}
+
+func fsList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	files, err := ioutil.ReadDir(path)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	var names []*runtime.Value
+
+	for _, file := range files {
+		names = append(names, runtime.NewStringValue(file.Name()))
+	}
+
+	return runtime.NewListValueOf(names), nil
+}
+
+// fsGlob returns the sorted paths matching a pattern. Matches of a relative pattern are relative too.
+func fsGlob(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	pattern := context.Args[0].Str
+
+	resolved, err := ResolvePath(context.Pos, pattern)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	matches, err := filepath.Glob(resolved)
+
+	if err != nil {
+		return runtime.NewErrorValue("pattern", err.Error()), nil
+	}
+
+	sort.Strings(matches)
+
+	var paths []*runtime.Value
+
+	for _, match := range matches {
+		if sandbox != "" && !withinSandbox(match) {
+			continue
+		}
+
+		if !filepath.IsAbs(pattern) {
+			if rel, err := filepath.Rel(baseDir(context.Pos), match); err == nil {
+				match = rel
+			}
+		}
+
+		paths = append(paths, runtime.NewStringValue(match))
+	}
+
+	return runtime.NewListValueOf(paths), nil
+}
+
+// fsStat returns a keyword list with :name, :size, :directory, :mode and :modified, in seconds since the Unix epoch.
+func fsStat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.NewListValueOf([]*runtime.Value{
+		runtime.NewKeywordValue("name"), runtime.NewStringValue(info.Name()),
+		runtime.NewKeywordValue("size"), runtime.NewNumberValueFromInt64(info.Size()),
+		runtime.NewKeywordValue("directory"), runtime.BooleanValueFor(info.IsDir()),
+		runtime.NewKeywordValue("mode"), runtime.NewStringValue(info.Mode().String()),
+		runtime.NewKeywordValue("modified"), runtime.NewNumberValueFromInt64(info.ModTime().Unix()),
+	}), nil
+}
+
+func fsMkdir(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	if context.Name == "mkdir-all" {
+		err = os.MkdirAll(path, 0777)
+	} else {
+		err = os.Mkdir(path, 0777)
+	}
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func fsRemove(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	if context.Name == "remove-all" {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func fsRename(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	from, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	to, err := ResolvePath(context.Pos, context.Args[1].Str)
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	if err := os.Rename(from, to); err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+// fsTemp creates a temporary file or directory and returns its path. An optional pattern names it,
+// a * in the pattern is replaced by a random string. With a sandbox, it is created inside the sandbox.
+func fsTemp(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	pattern := ""
+
+	if len(context.Args) > 0 {
+		if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+			return nil, err
+		}
+
+		pattern = context.Args[0].Str
+	}
+
+	var path string
+	var err error
+
+	if context.Name == "temp-dir" {
+		path, err = ioutil.TempDir(sandbox, pattern)
+	} else {
+		var file *os.File
+
+		if file, err = ioutil.TempFile(sandbox, pattern); err == nil {
+			path = file.Name()
+			err = file.Close()
+		}
+	}
+
+	if err != nil {
+		return ErrorValue(err), nil
+	}
+
+	return runtime.NewStringValue(path), nil
+}
+
+// fsExists handles exists?, file? and dir?. They return f if the path can't be accessed.
+func fsExists(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	path, err := ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return runtime.False, nil
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return runtime.False, nil
+	}
+
+	switch context.Name {
+	case "file?":
+		return runtime.BooleanValueFor(info.Mode().IsRegular()), nil
+	case "dir?":
+		return runtime.BooleanValueFor(info.IsDir()), nil
+	default:
+		return runtime.True, nil
+	}
+}
@@ -0,0 +1,103 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/raoulvdberge/risp/lexer"
+	"github.com/raoulvdberge/risp/runtime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrOutsideSandbox = errors.New("path is outside of the sandbox")
+
+// sandbox is the directory file system access is restricted to, or empty if there is no restriction.
+var sandbox string
+
+// SetSandbox restricts file system access to dir and everything below it.
+func SetSandbox(dir string) error {
+	abs, err := filepath.Abs(dir)
+
+	if err != nil {
+		return err
+	}
+
+	real, err := filepath.EvalSymlinks(abs)
+
+	if err != nil {
+		return err
+	}
+
+	sandbox = real
+
+	return nil
+}
+
+// Sandbox returns the directory file system access is restricted to, or an empty string.
+func Sandbox() string {
+	return sandbox
+}
+
+// ResolvePath resolves a path relative to the directory of the script at pos,
+// and checks that it doesn't escape the sandbox.
+func ResolvePath(pos *lexer.TokenPos, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir(pos), path)
+	}
+
+	path = filepath.Clean(path)
+
+	if sandbox != "" && !withinSandbox(path) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideSandbox)
+	}
+
+	return path, nil
+}
+
+// ErrorValue converts an error returned by the file system into an error value.
+func ErrorValue(err error) *runtime.Value {
+	if errors.Is(err, ErrOutsideSandbox) {
+		return runtime.NewErrorValue("sandbox", err.Error())
+	}
+
+	return runtime.NewIOErrorValue(err)
+}
+
+// baseDir returns the directory relative paths are resolved against: the directory of the
+// script at pos, or the working directory for code that doesn't come from a file.
+func baseDir(pos *lexer.TokenPos) string {
+	if source, ok := pos.Source.(*lexer.FileSource); ok {
+		return source.Dir()
+	}
+
+	dir, _ := os.Getwd()
+
+	return dir
+}
+
+func withinSandbox(path string) bool {
+	rel, err := filepath.Rel(sandbox, evalSymlinks(path))
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalSymlinks resolves the symbolic links in the longest existing prefix of path.
+func evalSymlinks(path string) string {
+	suffix := ""
+
+	for {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(real, suffix)
+		}
+
+		parent := filepath.Dir(path)
+
+		if parent == path {
+			return filepath.Join(path, suffix)
+		}
+
+		suffix = filepath.Join(filepath.Base(path), suffix)
+		path = parent
+	}
+}
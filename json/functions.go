@@ -0,0 +1,390 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/raoulvdberge/risp/runtime"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// JSON objects are decoded to keyword lists, arrays to lists, numbers to exact numbers and null to nil.
+// When encoding, a non-empty list with a keyword at every even position is written as an object,
+// any other list as an array. An empty object is decoded to an empty list, so it's encoded as [].
+var Symbols = runtime.Symtab{
+	"decode": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(jsonDecode, "decode"))),
+	"encode": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(jsonEncode, "encode"))),
+	"each":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(jsonEach, "each"))),
+}
+
+// maxExactExponent is the largest exponent of a number that is decoded to an exact number,
+// numbers with larger exponents are decoded to inexact numbers to keep them small.
+const maxExactExponent = 1000
+
+// sourceReader returns a reader for the argument of decode and each, a string or an input port.
+func sourceReader(context *runtime.FunctionCallContext, source *runtime.Value) (io.Reader, error) {
+	switch {
+	case source.Type == runtime.StringValue:
+		return strings.NewReader(source.Str), nil
+	case source.Type == runtime.PortValue && source.Port.IsInput():
+		return source.Port.Reader(), nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a string or an input port, got %s", context.Name, source.Type)
+	}
+}
+
+// errorValue converts a decoding error into an error value of kind :json, with the byte offset of the error.
+func errorValue(dec *json.Decoder, err error) *runtime.Value {
+	var syntaxErr *json.SyntaxError
+
+	offset := dec.InputOffset()
+
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return runtime.NewErrorValue("json", fmt.Sprintf("%s at byte offset %d", err, offset))
+}
+
+// jsonDecode decodes a JSON value from a string or the rest of an input port.
+func jsonDecode(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	r, err := sourceReader(context, context.Args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	dec := newDecoder(r)
+
+	value, err := decodeValue(dec)
+
+	if err != nil {
+		return errorValue(dec, err), nil
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+
+		return errorValue(dec, err), nil
+	}
+
+	return value, nil
+}
+
+// jsonEach decodes a JSON array one element at a time and calls a function with every element,
+// so large arrays don't have to be held in memory.
+func jsonEach(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	r, err := sourceReader(context, context.Args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	dec := newDecoder(r)
+
+	token, err := dec.Token()
+
+	if err != nil {
+		return errorValue(dec, err), nil
+	}
+
+	if token != json.Delim('[') {
+		return errorValue(dec, errors.New("expected an array")), nil
+	}
+
+	for dec.More() {
+		value, err := decodeValue(dec)
+
+		if err != nil {
+			return errorValue(dec, err), nil
+		}
+
+		if _, err := context.Args[1].Function.Call(context.Block, []*runtime.Value{value}, context.Pos); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return errorValue(dec, err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func newDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	return dec
+}
+
+func decodeValue(dec *json.Decoder) (*runtime.Value, error) {
+	token, err := dec.Token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		var l []*runtime.Value
+
+		for dec.More() {
+			if token == '{' {
+				key, err := dec.Token()
+
+				if err != nil {
+					return nil, err
+				}
+
+				l = append(l, runtime.NewKeywordValue(key.(string)))
+			}
+
+			value, err := decodeValue(dec)
+
+			if err != nil {
+				return nil, err
+			}
+
+			l = append(l, value)
+		}
+
+		// the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return runtime.NewListValueOf(l), nil
+	case string:
+		return runtime.NewStringValue(token), nil
+	case json.Number:
+		return decodeNumber(string(token))
+	case bool:
+		return runtime.BooleanValueFor(token), nil
+	default:
+		return runtime.Nil, nil
+	}
+}
+
+func decodeNumber(s string) (*runtime.Value, error) {
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exponent, err := strconv.Atoi(s[i+1:])
+
+		if err != nil || exponent > maxExactExponent || exponent < -maxExactExponent {
+			f, _, err := big.ParseFloat(s, 10, 53, big.ToNearestEven)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return runtime.NewNumberValueFromBigFloat(f), nil
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", s)
+	}
+
+	return runtime.NewNumberValueFromRat(r), nil
+}
+
+// jsonEncode encodes a value as JSON. The options are :pretty, to indent with two spaces, and :indent, a string to indent with.
+func jsonEncode(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a value to encode", context.Name)
+	}
+
+	options := context.Args[1:]
+
+	if len(options)%2 != 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected options as keyword and value pairs", context.Name)
+	}
+
+	e := &encoder{}
+
+	for i := 0; i < len(options); i += 2 {
+		key, value := options[i], options[i+1]
+
+		if key.Type != runtime.KeywordValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a keyword, got %s", context.Name, key.Type)
+		}
+
+		switch key.Keyword {
+		case "pretty":
+			if value.Type != runtime.BooleanValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a boolean", context.Name, key)
+			}
+
+			if value.Boolean {
+				e.indent = "  "
+			}
+		case "indent":
+			if value.Type != runtime.StringValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a string", context.Name, key)
+			}
+
+			e.indent = value.Str
+		default:
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown option %s", context.Name, key)
+		}
+	}
+
+	if err := e.encode(context.Args[0], 0); err != nil {
+		return runtime.NewErrorValue("json", err.Error()), nil
+	}
+
+	return runtime.NewStringValue(e.out.String()), nil
+}
+
+type encoder struct {
+	out    strings.Builder
+	indent string
+}
+
+func (e *encoder) newline(depth int) {
+	if e.indent != "" {
+		e.out.WriteString("\n" + strings.Repeat(e.indent, depth))
+	}
+}
+
+func (e *encoder) encode(v *runtime.Value, depth int) error {
+	switch v.Type {
+	case runtime.StringValue:
+		e.encodeString(v.Str)
+	case runtime.KeywordValue:
+		e.encodeString(v.Keyword)
+	case runtime.CharValue:
+		e.encodeString(string(v.Char))
+	case runtime.NumberValue:
+		s, err := encodeNumber(v)
+
+		if err != nil {
+			return err
+		}
+
+		e.out.WriteString(s)
+	case runtime.BooleanValue:
+		e.out.WriteString(strconv.FormatBool(v.Boolean))
+	case runtime.NilValue:
+		e.out.WriteString("null")
+	case runtime.ListValue, runtime.VectorValue:
+		// vectors are always arrays, lists of keywords and values are objects
+		items := runtime.Items(v)
+		object := v.Type == runtime.ListValue && isObject(v)
+
+		if object {
+			e.out.WriteString("{")
+		} else {
+			e.out.WriteString("[")
+		}
+
+		step := 1
+
+		if object {
+			step = 2
+		}
+
+		for i := 0; i < len(items); i += step {
+			if i > 0 {
+				e.out.WriteString(",")
+			}
+
+			e.newline(depth + 1)
+
+			if object {
+				e.encodeString(items[i].Keyword)
+				e.out.WriteString(":")
+
+				if e.indent != "" {
+					e.out.WriteString(" ")
+				}
+			}
+
+			if err := e.encode(items[i+step-1], depth+1); err != nil {
+				return err
+			}
+		}
+
+		if len(items) > 0 {
+			e.newline(depth)
+		}
+
+		if object {
+			e.out.WriteString("}")
+		} else {
+			e.out.WriteString("]")
+		}
+	default:
+		return fmt.Errorf("cannot encode a %s as JSON", v.Type)
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeString(s string) {
+	var buffer bytes.Buffer
+
+	enc := json.NewEncoder(&buffer)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+
+	e.out.WriteString(strings.TrimSuffix(buffer.String(), "\n"))
+}
+
+func encodeNumber(v *runtime.Value) (string, error) {
+	if !v.NumberIsFinite() || v.NumberIsNaN() {
+		return "", fmt.Errorf("cannot encode %s as JSON", v)
+	}
+
+	if v.NumberIsExact() {
+		format := runtime.NewNumberFormat()
+		format.Notation = "fixed"
+
+		if s, err := runtime.FormatNumber(v, format); err == nil {
+			return s, nil
+		}
+	}
+
+	if v.NumberKind == runtime.BigFloatNumber {
+		return v.BigFloat.Text('g', -1), nil
+	}
+
+	return strconv.FormatFloat(v.NumberToFloat64(), 'g', -1, 64), nil
+}
+
+// isObject checks if a list is a keyword list, which is encoded as an object.
+func isObject(l *runtime.Value) bool {
+	items := l.List.Values()
+
+	if len(items) == 0 || len(items)%2 != 0 {
+		return false
+	}
+
+	for i := 0; i < len(items); i += 2 {
+		if items[i].Type != runtime.KeywordValue {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,577 @@
+package lazy
+
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"math/big"
+)
+
+// Lazy sequences compute their items when they're first needed, see runtime.Seq. Every function that takes
+// a sequence also accepts a list or a vector, and the functions that return sequences don't realize any
+// items themselves. Sequences are realized by for, realize and first, or with the lazy-seq macro for
+// recursive sequences.
+var Symbols = runtime.Symtab{
+	"seq?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyIsSeq, "seq?"))),
+	"seq":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazySeq, "seq"))),
+	"iterate":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyIterate, "iterate"))),
+	"repeat":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyRepeat, "repeat"))),
+	"cycle":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyCycle, "cycle"))),
+	"lines":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyLines, "lines"))),
+	"cons":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyCons, "cons"))),
+	"first":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyFirst, "first"))),
+	"rest":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyRest, "rest"))),
+	"empty?":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyIsEmpty, "empty?"))),
+	"map":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyMap, "map"))),
+	"filter":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyFilter, "filter"))),
+	"take":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyTake, "take"))),
+	"take-while": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyTakeWhile, "take-while"))),
+	"drop":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyDrop, "drop"))),
+	"concat":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyConcat, "concat"))),
+	"realize":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(lazyRealize, "realize"))),
+}
+
+// split returns the first item and the rest of a sequence, it returns false if the sequence is empty.
+func split(s *runtime.Seq) (*runtime.Value, *runtime.Seq, bool, error) {
+	first, ok, err := s.First()
+
+	if err != nil || !ok {
+		return nil, nil, false, err
+	}
+
+	rest, err := s.Rest()
+
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return first, rest, true, nil
+}
+
+// seqArgument returns argument i, which should be a list, a vector or a lazy sequence, as a sequence.
+func seqArgument(context *runtime.FunctionCallContext, i int) (*runtime.Seq, error) {
+	if !runtime.IsIterable(context.Args[i]) {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be a list, a vector or a lazy sequence, got %s", context.Name, i+1, context.Args[i].Type)
+	}
+
+	return runtime.ToSeq(context.Args[i]), nil
+}
+
+// countArgument returns argument i, which should be an integer that isn't negative.
+func countArgument(context *runtime.FunctionCallContext, i int) (int64, error) {
+	n, err := runtime.Int64Argument(context, i)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: argument %d can't be negative, got %d", context.Name, i+1, n)
+	}
+
+	return n, nil
+}
+
+// detach returns the call context without its arguments, for sequences that call functions when they're
+// realized. Holding on to the arguments would keep the head of a sequence and everything realized after it in memory.
+func detach(context *runtime.FunctionCallContext) *runtime.FunctionCallContext {
+	detached := *context
+	detached.Args = nil
+
+	return &detached
+}
+
+// call calls a function while a sequence is realized. Functions are called with the block and position
+// of the call that created the sequence.
+func call(context *runtime.FunctionCallContext, fn *runtime.Function, args ...*runtime.Value) (*runtime.Value, error) {
+	return fn.Call(context.Block, args, context.Pos)
+}
+
+func lazyIsSeq(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.SeqValue), nil
+}
+
+// lazySeq is the lazy counterpart of list:seq, it returns the integers from low to high inclusive,
+// or all integers from low on without high.
+func lazySeq(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	var high *big.Int
+
+	if len(context.Args) == 2 {
+		if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+
+		h, err := runtime.IntegerArgument(context, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		high = h
+	} else if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	low, err := runtime.IntegerArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(rangeSeq(low, high)), nil
+}
+
+func rangeSeq(low *big.Int, high *big.Int) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		if high != nil && low.Cmp(high) > 0 {
+			return nil, nil, nil
+		}
+
+		return runtime.NewNumberValueFromBigInt(low), rangeSeq(new(big.Int).Add(low, big.NewInt(1)), high), nil
+	})
+}
+
+// lazyIterate returns x, (f x), (f (f x)) and so on.
+func lazyIterate(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(iterateSeq(detach(context), context.Args[0], context.Args[1].Function)), nil
+}
+
+func iterateSeq(context *runtime.FunctionCallContext, x *runtime.Value, fn *runtime.Function) *runtime.Seq {
+	return runtime.Cons(x, runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		next, err := call(context, fn, x)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		first, rest, _, err := split(iterateSeq(context, next, fn))
+
+		return first, rest, err
+	}))
+}
+
+// lazyRepeat repeats a value forever, or n times.
+func lazyRepeat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n := int64(-1)
+
+	if len(context.Args) == 2 {
+		if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+
+		count, err := countArgument(context, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		n = count
+	} else if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(repeatSeq(context.Args[0], n)), nil
+}
+
+// repeatSeq repeats x n times, or forever if n is negative.
+func repeatSeq(x *runtime.Value, n int64) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		if n == 0 {
+			return nil, nil, nil
+		}
+
+		if n < 0 {
+			return x, repeatSeq(x, n), nil
+		}
+
+		return x, repeatSeq(x, n-1), nil
+	})
+}
+
+// lazyCycle repeats the items of a list or sequence forever. Cycling an empty sequence gives an empty sequence.
+func lazyCycle(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(cycleSeq(s, s)), nil
+}
+
+func cycleSeq(s *runtime.Seq, current *runtime.Seq) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		first, rest, ok, err := split(current)
+
+		if err == nil && !ok {
+			first, rest, ok, err = split(s)
+		}
+
+		if err != nil || !ok {
+			return nil, nil, err
+		}
+
+		return first, cycleSeq(s, rest), nil
+	})
+}
+
+// lazyLines returns the lines of an input port, or of the standard input without one.
+// The port isn't closed at the end. If reading fails, the last item is an error value.
+func lazyLines(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	port := runtime.Stdin
+
+	if len(context.Args) > 0 {
+		if err := runtime.ValidateArguments(context, runtime.PortValue); err != nil {
+			return nil, err
+		}
+
+		port = context.Args[0].Port
+	}
+
+	if !port.IsInput() {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s is not an input port", context.Name, port.Name)
+	}
+
+	return runtime.NewSeqValue(linesSeq(port)), nil
+}
+
+func linesSeq(port *runtime.Port) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		line, ok, err := port.ReadLine()
+
+		if err != nil {
+			return runtime.NewIOErrorValue(err), runtime.EmptySeq(), nil
+		}
+
+		if !ok {
+			return nil, nil, nil
+		}
+
+		return runtime.NewStringValue(line), linesSeq(port), nil
+	})
+}
+
+// lazyCons returns a sequence of an item followed by a list, a sequence or nil.
+func lazyCons(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	if context.Args[1].Type == runtime.NilValue {
+		return runtime.NewSeqValue(runtime.Cons(context.Args[0], runtime.EmptySeq())), nil
+	}
+
+	rest, err := seqArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(runtime.Cons(context.Args[0], rest)), nil
+}
+
+// lazyFirst returns the first item of a sequence, or nil if it's empty.
+func lazyFirst(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	first, ok, err := s.First()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return runtime.Nil, nil
+	}
+
+	return first, nil
+}
+
+// lazyRest returns a sequence without its first item.
+func lazyRest(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := s.Rest()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(rest), nil
+}
+
+func lazyIsEmpty(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok, err := s.First()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(!ok), nil
+}
+
+func lazyMap(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(mapSeq(detach(context), s, context.Args[1].Function)), nil
+}
+
+func mapSeq(context *runtime.FunctionCallContext, s *runtime.Seq, fn *runtime.Function) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		item, rest, ok, err := split(s)
+
+		if err != nil || !ok {
+			return nil, nil, err
+		}
+
+		result, err := call(context, fn, item)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return result, mapSeq(context, rest, fn), nil
+	})
+}
+
+// lazyFilter keeps the items a predicate holds for, the predicate should return a boolean.
+func lazyFilter(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(filterSeq(detach(context), s, context.Args[1].Function, false)), nil
+}
+
+// filterSeq keeps the items fn holds for. With while set, it stops at the first item fn doesn't hold for.
+func filterSeq(context *runtime.FunctionCallContext, s *runtime.Seq, fn *runtime.Function, while bool) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		// s is advanced rather than copied, so the skipped items can be freed
+		for {
+			item, rest, ok, err := split(s)
+
+			if err != nil || !ok {
+				return nil, nil, err
+			}
+
+			result, err := call(context, fn, item)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if result.Type != runtime.BooleanValue {
+				return nil, nil, runtime.NewRuntimeError(context.Pos, "%s: expected a boolean return value, got %s", context.Name, result.Type)
+			}
+
+			if result.Boolean {
+				return item, filterSeq(context, rest, fn, while), nil
+			}
+
+			if while {
+				return nil, nil, nil
+			}
+
+			s = rest
+		}
+	})
+}
+
+func lazyTake(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(takeSeq(s, n)), nil
+}
+
+func takeSeq(s *runtime.Seq, n int64) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		if n == 0 {
+			return nil, nil, nil
+		}
+
+		item, rest, ok, err := split(s)
+
+		if err != nil || !ok {
+			return nil, nil, err
+		}
+
+		return item, takeSeq(rest, n-1), nil
+	})
+}
+
+// lazyTakeWhile returns the items before the first item a predicate doesn't hold for.
+func lazyTakeWhile(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(filterSeq(detach(context), s, context.Args[1].Function, true)), nil
+}
+
+func lazyDrop(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	s, err := seqArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewSeqValue(runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		// s is advanced rather than copied, so the dropped items can be freed
+		for ; n > 0; n-- {
+			_, rest, ok, err := split(s)
+
+			if err != nil || !ok {
+				return nil, nil, err
+			}
+
+			s = rest
+		}
+
+		item, rest, _, err := split(s)
+
+		return item, rest, err
+	})), nil
+}
+
+// lazyConcat returns the items of every list or sequence, one after another.
+func lazyConcat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	var seqs []*runtime.Seq
+
+	for i := range context.Args {
+		s, err := seqArgument(context, i)
+
+		if err != nil {
+			return nil, err
+		}
+
+		seqs = append(seqs, s)
+	}
+
+	return runtime.NewSeqValue(concatSeq(seqs)), nil
+}
+
+func concatSeq(seqs []*runtime.Seq) *runtime.Seq {
+	return runtime.NewSeq(func() (*runtime.Value, *runtime.Seq, error) {
+		for len(seqs) > 0 {
+			item, rest, ok, err := split(seqs[0])
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if ok {
+				return item, concatSeq(append([]*runtime.Seq{rest}, seqs[1:]...)), nil
+			}
+
+			seqs = seqs[1:]
+		}
+
+		return nil, nil, nil
+	})
+}
+
+// lazyRealize returns the items of a sequence as a list. It never returns for an infinite sequence,
+// take a part of it first.
+func lazyRealize(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	if !runtime.IsIterable(context.Args[0]) {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: argument 1 should be a list, a vector or a lazy sequence, got %s", context.Name, context.Args[0].Type)
+	}
+
+	var l []*runtime.Value
+
+	err := runtime.Iterate(context.Args[0], func(item *runtime.Value) (bool, error) {
+		l = append(l, item)
+
+		return true, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
@@ -6,8 +6,9 @@ import (
 )
 
 type SyntaxError struct {
-	pos     *TokenPos
-	message string
+	pos        *TokenPos
+	message    string
+	incomplete bool
 }
 
 func NewSyntaxError(pos *TokenPos, format string, data ...interface{}) *SyntaxError {
@@ -17,6 +18,27 @@ func NewSyntaxError(pos *TokenPos, format string, data ...interface{}) *SyntaxEr
 	}
 }
 
+// NewIncompleteSyntaxError returns an error for input that ends before a string, list or quote is complete.
+// Readers use it to know that more input could make the input valid.
+func NewIncompleteSyntaxError(pos *TokenPos, format string, data ...interface{}) *SyntaxError {
+	err := NewSyntaxError(pos, format, data...)
+	err.incomplete = true
+
+	return err
+}
+
 func (e *SyntaxError) Error() string {
 	return fmt.Sprintf(util.Red("syntax error:")+" %s(%d:%d): %s", e.pos.Source.Name(), e.pos.Line, e.pos.Col, e.message)
 }
+
+func (e *SyntaxError) Pos() *TokenPos {
+	return e.pos
+}
+
+func (e *SyntaxError) Message() string {
+	return e.message
+}
+
+func (e *SyntaxError) Incomplete() bool {
+	return e.incomplete
+}
@@ -1,9 +1,12 @@
 package lexer
 
 import (
+	"fmt"
+	"github.com/raoulvdberge/risp/util"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
@@ -92,21 +95,103 @@ func (l *Lexer) hasNext() bool {
 	return l.pos < len(l.data)
 }
 
+func (l *Lexer) canPeek(amount int) bool {
+	return l.pos+amount < len(l.data)
+}
+
+// isNumberStart checks if a number literal starts at the current position.
+func (l *Lexer) isNumberStart() bool {
+	switch {
+	case isNumber(l.current()):
+		return true
+	case l.current() == '+' || l.current() == '-':
+		return (l.canPeek(1) && isNumber(l.peek(1))) || l.isSpecialNumber()
+	case l.current() == '#':
+		return l.canPeek(1) && (l.peek(1) == 'e' || l.peek(1) == 'i')
+	default:
+		return false
+	}
+}
+
+// isSpecialNumber checks if one of the special inexact values +inf.0, -inf.0 or +nan.0 starts at the current position.
+func (l *Lexer) isSpecialNumber() bool {
+	rest := l.data[l.pos:]
+
+	for _, special := range []string{"+inf.0", "-inf.0", "+nan.0", "-nan.0"} {
+		if strings.HasPrefix(rest, special) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (l *Lexer) lexNumber() error {
+	exact := false
+
+	if l.current() == '#' {
+		exact = l.peek(1) == 'e'
+
+		l.consume()
+		l.consume()
+
+		if !l.hasNext() || !(isNumber(l.current()) || ((l.current() == '+' || l.current() == '-') && l.canPeek(1) && isNumber(l.peek(1))) || l.isSpecialNumber()) {
+			return NewSyntaxError(l.newPos(), "expected a number after exactness prefix")
+		}
+	}
+
+	if l.isSpecialNumber() {
+		if exact {
+			return NewSyntaxError(l.newPos(), "%s has no exact representation", l.data[l.pos:l.pos+6])
+		}
+
+		for i := 0; i < 6; i++ {
+			l.consume()
+		}
+
+		return l.finishNumber()
+	}
+
 	if l.current() == '+' || l.current() == '-' {
 		l.consume()
 	}
 
-	for l.hasNext() && isNumber(l.current()) {
+	err := l.lexInteger(true)
+
+	if err != nil {
+		return err
+	}
+
+	if l.hasNext() && l.current() == '/' {
 		l.consume()
 
-		if !l.isEOF() && l.current() == '.' {
-			if strings.Contains(l.buffer(), ".") {
-				return NewSyntaxError(l.newPos(), "malformed number")
-			}
+		start := l.pos
 
-			l.consume()
+		if !l.hasNext() || !isNumber(l.current()) {
+			return NewSyntaxError(l.newPos(), "expected a denominator after '/'")
+		}
+
+		if err := l.lexInteger(false); err != nil {
+			return err
+		}
+
+		denominator := l.data[start:l.pos]
+
+		if base, _ := util.RadixPrefix(denominator); base != 10 {
+			denominator = denominator[2:]
 		}
+
+		if strings.Trim(denominator, "0_") == "" {
+			return NewSyntaxError(l.newPos(), "zero denominator in rational literal")
+		}
+	}
+
+	return l.finishNumber()
+}
+
+func (l *Lexer) finishNumber() error {
+	if l.hasNext() && (IsIdentifierStart(l.current()) || isNumber(l.current()) || l.current() == '.' || l.current() == '_' || l.current() == '/') {
+		return NewSyntaxError(l.newPos(), "unexpected character '%c' in number literal", l.current())
 	}
 
 	l.addToken(Number)
@@ -114,19 +199,232 @@ func (l *Lexer) lexNumber() error {
 	return nil
 }
 
+// lexInteger lexes an integer with an optional radix prefix. If decimal is true,
+// a decimal integer may also have a fractional part and an exponent.
+func (l *Lexer) lexInteger(decimal bool) error {
+	if base, name := util.RadixPrefix(l.data[l.pos:]); base != 10 {
+		l.consume()
+		l.consume()
+
+		digits, err := l.lexDigits(base, name)
+
+		if err != nil {
+			return err
+		}
+
+		if digits == 0 {
+			return NewSyntaxError(l.newPos(), "expected %s digits after '%s'", name, l.data[l.pos-2:l.pos])
+		}
+
+		return nil
+	}
+
+	if _, err := l.lexDigits(10, "decimal"); err != nil {
+		return err
+	}
+
+	if !decimal {
+		return nil
+	}
+
+	fractional := false
+
+	if l.hasNext() && l.current() == '.' {
+		l.consume()
+
+		digits, err := l.lexDigits(10, "decimal")
+
+		if err != nil {
+			return err
+		}
+
+		if digits == 0 {
+			return NewSyntaxError(l.newPos(), "expected digits after decimal point")
+		}
+
+		fractional = true
+	}
+
+	if l.hasNext() && (l.current() == 'e' || l.current() == 'E') {
+		l.consume()
+
+		if l.hasNext() && (l.current() == '+' || l.current() == '-') {
+			l.consume()
+		}
+
+		digits, err := l.lexDigits(10, "decimal")
+
+		if err != nil {
+			return err
+		}
+
+		if digits == 0 {
+			return NewSyntaxError(l.newPos(), "expected digits in exponent")
+		}
+
+		fractional = true
+	}
+
+	if fractional && l.hasNext() && l.current() == '/' {
+		return NewSyntaxError(l.newPos(), "numerator of a rational literal must be an integer")
+	}
+
+	return nil
+}
+
+// lexDigits lexes digits of the given base, separated by optional underscores.
+// It returns the amount of digits lexed.
+func (l *Lexer) lexDigits(base int, name string) (int, error) {
+	digits := 0
+
+	for l.hasNext() {
+		r := l.current()
+
+		if r == '_' {
+			if digits == 0 || !l.canPeek(1) || digitValue(l.peek(1)) < 0 || digitValue(l.peek(1)) >= base {
+				return digits, NewSyntaxError(l.newPos(), "digit separator '_' must be placed between digits")
+			}
+
+			l.consume()
+
+			continue
+		}
+
+		value := digitValue(r)
+
+		if value < 0 || (value >= 10 && base <= 10) {
+			break
+		}
+
+		if value >= base {
+			return digits, NewSyntaxError(l.newPos(), "invalid digit '%c' in %s literal", r, name)
+		}
+
+		l.consume()
+
+		digits++
+	}
+
+	return digits, nil
+}
+
 func (l *Lexer) lexString() error {
 	l.ignore(1)
 
 	for l.hasNext() && l.current() != '"' {
+		if l.current() == '\\' && l.canPeek(1) {
+			l.consume()
+		}
+
 		l.consume()
 	}
 
 	if l.isEOF() || l.current() != '"' {
-		return NewSyntaxError(l.newPos(), "unclosed string literal")
+		return NewIncompleteSyntaxError(l.newPos(), "unclosed string literal")
 	}
 
 	t := l.addToken(String)
-	t.Data, _ = strconv.Unquote("\"" + t.Data + "\"")
+	data, err := strconv.Unquote("\"" + t.Data + "\"")
+
+	if err != nil {
+		return NewSyntaxError(t.Pos, "invalid escape sequence in string literal")
+	}
+
+	t.Data = data
+
+	l.ignore(1)
+
+	return nil
+}
+
+// lexChar lexes a character literal: #\a, a named character like #\space or a code point like #\u{1F600}.
+// The token data is the character itself.
+func (l *Lexer) lexChar() error {
+	l.ignore(2)
+
+	if !l.hasNext() {
+		return NewSyntaxError(l.newPos(), "expected a character after '#\\'")
+	}
+
+	r, size := utf8.DecodeRuneInString(l.data[l.pos:])
+
+	for i := 0; i < size; i++ {
+		l.consume()
+	}
+
+	if r == 'u' && l.hasNext() && l.current() == '{' {
+		for l.hasNext() && l.current() != '}' {
+			l.consume()
+		}
+
+		if !l.hasNext() {
+			return NewSyntaxError(l.newPos(), "unclosed character code point")
+		}
+
+		l.consume()
+
+		code, err := strconv.ParseUint(l.buffer()[2:len(l.buffer())-1], 16, 32)
+
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return NewSyntaxError(l.newPos(), "invalid character code point '%s'", l.buffer()[2:len(l.buffer())-1])
+		}
+
+		l.addToken(Char).Data = string(rune(code))
+
+		return nil
+	}
+
+	if unicode.IsLetter(r) {
+		for l.hasNext() && IsIdentifierPart(l.current()) {
+			l.consume()
+		}
+	}
+
+	name := l.buffer()
+
+	if utf8.RuneCountInString(name) > 1 {
+		named, ok := charNames[name]
+
+		if !ok {
+			return NewSyntaxError(l.newPos(), "unknown character name '%s'", name)
+		}
+
+		r = named
+	}
+
+	l.addToken(Char).Data = string(r)
+
+	return nil
+}
+
+// lexBytes lexes a bytes literal: #x"" with pairs of hex digits, which may be separated by whitespace.
+// The token data is the bytes themselves.
+func (l *Lexer) lexBytes() error {
+	l.ignore(3)
+
+	var data []byte
+
+	for l.hasNext() && l.current() != '"' {
+		if unicode.IsSpace(l.current()) {
+			l.ignore(1)
+
+			continue
+		}
+
+		if !l.canPeek(1) || !isHexDigit(l.current()) || !isHexDigit(l.peek(1)) {
+			return NewSyntaxError(l.newPos(), "expected a pair of hex digits in bytes literal")
+		}
+
+		data = append(data, byte(digitValue(l.current())<<4|digitValue(l.peek(1))))
+
+		l.ignore(2)
+	}
+
+	if l.isEOF() {
+		return NewIncompleteSyntaxError(l.newPos(), "unclosed bytes literal")
+	}
+
+	l.addToken(Bytes).Data = string(data)
 
 	l.ignore(1)
 
@@ -165,7 +463,19 @@ func (l *Lexer) lexComment() {
 func (l *Lexer) Lex() error {
 	for !l.isEOF() {
 		switch {
-		case isNumber(l.current()), (l.current() == '+' || l.current() == '-') && l.hasNext() && isNumber(l.peek(1)):
+		case l.current() == '#' && l.canPeek(1) && l.peek(1) == '\\':
+			err := l.lexChar()
+
+			if err != nil {
+				return err
+			}
+		case l.current() == '#' && l.canPeek(2) && l.peek(1) == 'x' && l.peek(2) == '"':
+			err := l.lexBytes()
+
+			if err != nil {
+				return err
+			}
+		case l.isNumberStart():
 			err := l.lexNumber()
 
 			if err != nil {
@@ -190,7 +500,10 @@ func (l *Lexer) Lex() error {
 			if err != nil {
 				return err
 			}
-		case l.current() == '(', l.current() == ')', l.current() == '\'':
+		case l.current() == '#' && l.canPeek(1) && l.peek(1) == '{':
+			l.consume()
+			l.lexSeparator()
+		case l.current() == '(', l.current() == ')', l.current() == '}', l.current() == '[', l.current() == ']', l.current() == '\'':
 			l.lexSeparator()
 		case l.current() == ';':
 			l.lexComment()
@@ -204,14 +517,60 @@ func (l *Lexer) Lex() error {
 	return nil
 }
 
+var charNames = map[string]rune{
+	"space":     ' ',
+	"newline":   '\n',
+	"tab":       '\t',
+	"return":    '\r',
+	"nul":       0,
+	"null":      0,
+	"alarm":     '\a',
+	"backspace": '\b',
+	"delete":    0x7f,
+	"escape":    0x1b,
+}
+
+// CharName returns how a character is written in a character literal, without the #\ prefix.
+func CharName(r rune) string {
+	for name, named := range charNames {
+		if named == r && name != "null" {
+			return name
+		}
+	}
+
+	if !unicode.IsPrint(r) {
+		return fmt.Sprintf("u{%X}", r)
+	}
+
+	return string(r)
+}
+
+func isHexDigit(r rune) bool {
+	return digitValue(r) >= 0 && digitValue(r) < 16
+}
+
 func isNumber(r rune) bool {
 	return r >= '0' && r <= '9'
 }
 
+// digitValue returns the value of a digit in bases up to 36, or -1 if r isn't a digit.
+func digitValue(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0')
+	case r >= 'a' && r <= 'z':
+		return int(r-'a') + 10
+	case r >= 'A' && r <= 'Z':
+		return int(r-'A') + 10
+	default:
+		return -1
+	}
+}
+
 func IsIdentifierStart(r rune) bool {
 	return unicode.IsLetter(r)
 }
 
 func IsIdentifierPart(r rune) bool {
-	return IsIdentifierStart(r) || unicode.IsNumber(r) || r == '-' || r == ':'
+	return IsIdentifierStart(r) || unicode.IsNumber(r) || r == '-' || r == ':' || r == '?' || r == '!' || r == '>' || r == '_'
 }
@@ -23,6 +23,11 @@ func (f *FileSource) Data() string {
 	return f.file.Data
 }
 
+// Dir returns the absolute directory of the file.
+func (f *FileSource) Dir() string {
+	return f.file.Dir
+}
+
 type StringSource struct {
 	name string
 	data string
@@ -8,6 +8,8 @@ const (
 	Identifier
 	Keyword
 	Separator
+	Char
+	Bytes
 )
 
 type Token struct {
@@ -35,9 +37,9 @@ func (t *Token) IsTypeAndData(typ TokenType, data string) bool {
 }
 
 func (t *Token) DepthModifier() int {
-	if t.IsTypeAndData(Separator, "(") {
+	if t.IsTypeAndData(Separator, "(") || t.IsTypeAndData(Separator, "#{") || t.IsTypeAndData(Separator, "[") {
 		return 1
-	} else if t.IsTypeAndData(Separator, ")") {
+	} else if t.IsTypeAndData(Separator, ")") || t.IsTypeAndData(Separator, "}") || t.IsTypeAndData(Separator, "]") {
 		return -1
 	}
 	return 0
@@ -0,0 +1,535 @@
+package list
+
+import "github.com/raoulvdberge/risp/runtime"
+
+// The functions in this file take function values, like builtins, functions declared with defun and lambdas.
+// map, filter and reduce are also macros that take an identifier and a body, the macros call these functions
+// when they're called with a function instead. Like the rest of the namespace, they take lists or vectors,
+// and sequences they return are of the same kind as the one they're given.
+
+// call calls fn with args and returns its result.
+func call(context *runtime.FunctionCallContext, fn *runtime.Function, args ...*runtime.Value) (*runtime.Value, error) {
+	return fn.Call(context.Block, args, context.Pos)
+}
+
+// test calls a predicate with an item, which should return a boolean.
+func test(context *runtime.FunctionCallContext, fn *runtime.Function, item *runtime.Value) (bool, error) {
+	result, err := call(context, fn, item)
+
+	if err != nil {
+		return false, err
+	}
+
+	if result.Type != runtime.BooleanValue {
+		return false, runtime.NewRuntimeError(context.Pos, "%s: expected a boolean return value, got %s", context.Name, result.Type)
+	}
+
+	return result.Boolean, nil
+}
+
+// countArgument returns argument i, which should be a positive integer.
+func countArgument(context *runtime.FunctionCallContext, i int) (int, error) {
+	n, err := runtime.Int64Argument(context, i)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 1 {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be positive, got %d", context.Name, i+1, n)
+	}
+
+	return int(n), nil
+}
+
+// listsArguments validates that all arguments, except for the last one if fn is set, are lists or vectors.
+// It returns their items and the length of the shortest one.
+func listsArguments(context *runtime.FunctionCallContext, fn bool) ([][]*runtime.Value, int, error) {
+	args := context.Args
+
+	if fn {
+		if len(args) < 2 || args[len(args)-1].Type != runtime.FunctionValue {
+			return nil, 0, runtime.NewRuntimeError(context.Pos, "%s: expected one or more lists or vectors followed by a function", context.Name)
+		}
+
+		args = args[:len(args)-1]
+	} else if len(args) < 1 {
+		return nil, 0, runtime.NewRuntimeError(context.Pos, "%s: expected one or more lists or vectors", context.Name)
+	}
+
+	lists := make([][]*runtime.Value, len(args))
+	shortest := -1
+
+	for i, arg := range args {
+		if !runtime.IsSequence(arg) {
+			return nil, 0, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be a list or a vector, got %s", context.Name, i+1, arg.Type)
+		}
+
+		lists[i] = runtime.Items(arg)
+
+		if shortest == -1 || len(lists[i]) < shortest {
+			shortest = len(lists[i])
+		}
+	}
+
+	return lists, shortest, nil
+}
+
+// groups collects values by key, keeping the keys in the order they were first added.
+type groups struct {
+	keys    []*runtime.Value
+	indices map[uint64][]int
+}
+
+func newGroups() *groups {
+	return &groups{indices: make(map[uint64][]int)}
+}
+
+// index returns the index of a key, adding it if it's new.
+func (g *groups) index(key *runtime.Value) (int, bool) {
+	hash := key.Hash()
+
+	for _, i := range g.indices[hash] {
+		if g.keys[i].Equals(key) {
+			return i, false
+		}
+	}
+
+	g.keys = append(g.keys, key)
+	g.indices[hash] = append(g.indices[hash], len(g.keys)-1)
+
+	return len(g.keys) - 1, true
+}
+
+func listMapFunction(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		result, err := call(context, context.Args[1].Function, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		l = append(l, result)
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+func listFilterFunction(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		ok, err := test(context, context.Args[1].Function, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			l = append(l, item)
+		}
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// listReduceFunction combines the items of a non-empty list with a function of two arguments, from left to right.
+func listReduceFunction(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	list := runtime.Items(context.Args[0])
+
+	if len(list) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: empty list", context.Name)
+	}
+
+	return fold(context, list[0], list[1:], context.Args[1].Function)
+}
+
+// listFold is like reduce, but starts with an initial value, so the list may be empty.
+func listFold(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.AnyValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	return fold(context, context.Args[1], runtime.Items(context.Args[0]), context.Args[2].Function)
+}
+
+func fold(context *runtime.FunctionCallContext, result *runtime.Value, items []*runtime.Value, fn *runtime.Function) (*runtime.Value, error) {
+	for _, item := range items {
+		r, err := call(context, fn, result, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = r
+	}
+
+	return result, nil
+}
+
+func listForEach(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		if _, err := call(context, context.Args[1].Function, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return runtime.Nil, nil
+}
+
+// listFind returns the first item a predicate holds for with find, or its index with index-of.
+// It returns nil if there's no such item. any? and every? check if the predicate holds for any or every item.
+func listFind(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	for i, item := range runtime.Items(context.Args[0]) {
+		ok, err := test(context, context.Args[1].Function, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case ok && context.Name == "find":
+			return item, nil
+		case ok && context.Name == "index-of":
+			return runtime.NewNumberValueFromInt64(int64(i)), nil
+		case ok && context.Name == "any?":
+			return runtime.True, nil
+		case !ok && context.Name == "every?":
+			return runtime.False, nil
+		}
+	}
+
+	switch context.Name {
+	case "any?":
+		return runtime.False, nil
+	case "every?":
+		return runtime.True, nil
+	default:
+		return runtime.Nil, nil
+	}
+}
+
+// listWhile returns the items before the first item a predicate doesn't hold for with take-while,
+// or the items from that item on with drop-while.
+func listWhile(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	list := runtime.Items(context.Args[0])
+	i := 0
+
+	for ; i < len(list); i++ {
+		ok, err := test(context, context.Args[1].Function, list[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			break
+		}
+	}
+
+	var l []*runtime.Value
+
+	if context.Name == "take-while" {
+		l = append(l, list[:i]...)
+	} else {
+		l = append(l, list[i:]...)
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// listPartition returns a list of two lists: the items a predicate holds for and the other items.
+func listPartition(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	var matching, other []*runtime.Value
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		ok, err := test(context, context.Args[1].Function, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			matching = append(matching, item)
+		} else {
+			other = append(other, item)
+		}
+	}
+
+	return runtime.NewListValueOf([]*runtime.Value{runtime.NewSequenceOf(context.Args[0], matching), runtime.NewSequenceOf(context.Args[0], other)}), nil
+}
+
+// listGroupBy groups items by the result of a function. It returns a list of keys followed by their
+// items, in the order the keys were first returned, so keyword keys can be looked up with get-key.
+func listGroupBy(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	g := newGroups()
+
+	var items [][]*runtime.Value
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		key, err := call(context, context.Args[1].Function, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		i, added := g.index(key)
+
+		if added {
+			items = append(items, nil)
+		}
+
+		items[i] = append(items[i], item)
+	}
+
+	var l []*runtime.Value
+
+	for i, key := range g.keys {
+		l = append(l, key, runtime.NewListValueOf(items[i]))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// listFrequencies counts how often every distinct item occurs. Like group-by, it returns a list of items
+// followed by their counts, in the order they first occur.
+func listFrequencies(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	g := newGroups()
+
+	var counts []int64
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		i, added := g.index(item)
+
+		if added {
+			counts = append(counts, 0)
+		}
+
+		counts[i]++
+	}
+
+	var l []*runtime.Value
+
+	for i, key := range g.keys {
+		l = append(l, key, runtime.NewNumberValueFromInt64(counts[i]))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// listFlatMap calls a function that returns a list with every item, and joins the results.
+func listFlatMap(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		result, err := call(context, context.Args[1].Function, item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !runtime.IsSequence(result) {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a list or vector return value, got %s", context.Name, result.Type)
+		}
+
+		l = append(l, runtime.Items(result)...)
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// listZip returns lists of the items at the same index in one or more lists, up to the length of the shortest list.
+func listZip(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	lists, shortest, err := listsArguments(context, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for i := 0; i < shortest; i++ {
+		var tuple []*runtime.Value
+
+		for _, list := range lists {
+			tuple = append(tuple, list[i])
+		}
+
+		l = append(l, runtime.NewListValueOf(tuple))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// listZipWith calls a function with the items at the same index in one or more lists, up to the length
+// of the shortest list. The function is the last argument, like (list:zip-with xs ys +).
+func listZipWith(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	lists, shortest, err := listsArguments(context, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fn := context.Args[len(context.Args)-1].Function
+	var l []*runtime.Value
+
+	for i := 0; i < shortest; i++ {
+		args := make([]*runtime.Value, len(lists))
+
+		for j, list := range lists {
+			args[j] = list[i]
+		}
+
+		result, err := call(context, fn, args...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		l = append(l, result)
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// listInterleave returns the first item of every list, then the second item of every list and so on,
+// up to the length of the shortest list.
+func listInterleave(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	lists, shortest, err := listsArguments(context, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for i := 0; i < shortest; i++ {
+		for _, list := range lists {
+			l = append(l, list[i])
+		}
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// listDistinct returns the items of a list without duplicates, keeping the first occurrence.
+func listDistinct(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	seen := runtime.NewSet()
+	var l []*runtime.Value
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		if seen.Insert(item) {
+			l = append(l, item)
+		}
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// listChunk splits a list into lists of n items, the last list has fewer items if there aren't enough.
+func listChunk(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list := runtime.Items(context.Args[0])
+	var l []*runtime.Value
+
+	for i := 0; i < len(list); i += n {
+		chunk := append([]*runtime.Value{}, list[i:min(i+n, len(list))]...)
+
+		l = append(l, runtime.NewSequenceOf(context.Args[0], chunk))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// listWindow returns every run of n consecutive items, starting every item or every step items if it's given.
+func listWindow(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	step := 1
+
+	if len(context.Args) == 3 {
+		if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+
+		s, err := countArgument(context, 2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		step = s
+	} else if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list := runtime.Items(context.Args[0])
+	var l []*runtime.Value
+
+	for i := 0; i+n <= len(list); i += step {
+		window := append([]*runtime.Value{}, list[i:i+n]...)
+
+		l = append(l, runtime.NewSequenceOf(context.Args[0], window))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
@@ -1,25 +1,58 @@
 package list
 
-import "github.com/raoulvdberge/risp/runtime"
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"math/big"
+)
 
 var Symbols = runtime.Symtab{
-	"seq":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSeq, "seq"))),
-	"contains":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listContains, "contains"))),
-	"contains-key": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listContainsKey, "contains-key"))),
-	"push":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listPush, "push"))),
-	"push-left":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listPushLeft, "push-left"))),
-	"size":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSize, "size"))),
-	"get":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listGet, "get"))),
-	"get-key":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listGetKey, "get-key"))),
-	"set":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSet, "set"))),
-	"set-key":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSetKey, "set-key"))),
-	"drop":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listDrop, "drop"))),
-	"drop-left":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listDropLeft, "drop-left"))),
-	"join":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listJoin, "join"))),
-	"range":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listRange, "range"))),
-	"reverse":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listReverse, "reverse"))),
-	"remove":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listRemove, "remove"))),
-	"remove-key":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listRemoveKey, "remove-key"))),
+	"seq":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSeq, "seq"))),
+	"contains":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listContains, "contains"))),
+	"contains-key":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listContainsKey, "contains-key"))),
+	"push":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listPush, "push"))),
+	"push-left":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listPushLeft, "push-left"))),
+	"size":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSize, "size"))),
+	"get":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listGet, "get"))),
+	"get-key":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listGetKey, "get-key"))),
+	"set":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSet, "set"))),
+	"set-key":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSetKey, "set-key"))),
+	"drop":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listDrop, "drop"))),
+	"drop-left":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listDropLeft, "drop-left"))),
+	"join":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listJoin, "join"))),
+	"range":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listRange, "range"))),
+	"reverse":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listReverse, "reverse"))),
+	"remove":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listRemove, "remove"))),
+	"remove-key":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listRemoveKey, "remove-key"))),
+	"sort":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSort, "sort"))),
+	"sort-by":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSortBy, "sort-by"))),
+	"sort-with":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listSortWith, "sort-with"))),
+	"binary-search": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listBinarySearch, "binary-search"))),
+	"min":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listExtreme, "min"))),
+	"max":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listExtreme, "max"))),
+	"min-by":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listExtreme, "min-by"))),
+	"max-by":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listExtreme, "max-by"))),
+	"top-n":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listTopN, "top-n"))),
+	"map":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listMapFunction, "map"))),
+	"filter":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFilterFunction, "filter"))),
+	"reduce":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listReduceFunction, "reduce"))),
+	"fold":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFold, "fold"))),
+	"for-each":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listForEach, "for-each"))),
+	"any?":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFind, "any?"))),
+	"every?":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFind, "every?"))),
+	"find":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFind, "find"))),
+	"index-of":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFind, "index-of"))),
+	"take-while":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listWhile, "take-while"))),
+	"drop-while":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listWhile, "drop-while"))),
+	"partition":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listPartition, "partition"))),
+	"group-by":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listGroupBy, "group-by"))),
+	"frequencies":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFrequencies, "frequencies"))),
+	"flat-map":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listFlatMap, "flat-map"))),
+	"zip":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listZip, "zip"))),
+	"zip-with":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listZipWith, "zip-with"))),
+	"interleave":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listInterleave, "interleave"))),
+	"distinct":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listDistinct, "distinct"))),
+	"chunk":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listChunk, "chunk"))),
+	"window":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(listWindow, "window"))),
 }
 
 func listSeq(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -27,28 +60,37 @@ func listSeq(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 		return nil, err
 	}
 
-	low := context.Args[0].NumberToInt64()
-	high := context.Args[1].NumberToInt64()
+	low, err := runtime.IntegerArgument(context, 0)
 
-	if low > high {
-		return nil, runtime.NewRuntimeError(context.Pos, "invalid argument(s), low can't be higher than high (%d > %d)", low, high)
+	if err != nil {
+		return nil, err
 	}
 
-	l := runtime.NewListValue()
+	high, err := runtime.IntegerArgument(context, 1)
 
-	for i := low; i <= high; i++ {
-		l.List = append(l.List, runtime.NewNumberValueFromInt64(i))
+	if err != nil {
+		return nil, err
 	}
 
-	return l, nil
+	if low.Cmp(high) > 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "invalid argument(s), low can't be higher than high (%s > %s)", low, high)
+	}
+
+	var l []*runtime.Value
+
+	for i := new(big.Int).Set(low); i.Cmp(high) <= 0; i.Add(i, big.NewInt(1)) {
+		l = append(l, runtime.NewNumberValueFromBigInt(i))
+	}
+
+	return runtime.NewListValueOf(l), nil
 }
 
 func listContains(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.AnyValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.AnyValue); err != nil {
 		return nil, err
 	}
 
-	for _, item := range context.Args[0].List {
+	for _, item := range runtime.Items(context.Args[0]) {
 		if item.Equals(context.Args[1]) {
 			return runtime.True, nil
 		}
@@ -62,7 +104,7 @@ func listContainsKey(context *runtime.FunctionCallContext) (*runtime.Value, erro
 		return nil, err
 	}
 
-	l := context.Args[0].List
+	l := context.Args[0].List.Values()
 
 	for i := 0; i < len(l); i++ {
 		if l[i].Type == runtime.KeywordValue && l[i].Keyword == context.Args[1].Keyword && i+1 < len(l) {
@@ -74,46 +116,58 @@ func listContainsKey(context *runtime.FunctionCallContext) (*runtime.Value, erro
 }
 
 func listPush(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.AnyValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.AnyValue); err != nil {
 		return nil, err
 	}
 
-	context.Args[0].List = append(context.Args[0].List, context.Args[1])
+	if context.Args[0].Type == runtime.VectorValue {
+		context.Args[0].Vector = context.Args[0].Vector.Push(context.Args[1])
+	} else {
+		context.Args[0].List = context.Args[0].List.Push(context.Args[1])
+	}
 
 	return context.Args[0], nil
 }
 
 func listPushLeft(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.AnyValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.AnyValue); err != nil {
 		return nil, err
 	}
 
-	context.Args[0].List = append([]*runtime.Value{context.Args[1]}, context.Args[0].List...)
+	if context.Args[0].Type == runtime.VectorValue {
+		context.Args[0].Vector = runtime.NewVector(append([]*runtime.Value{context.Args[1]}, context.Args[0].Vector.Values()...))
+	} else {
+		context.Args[0].List = context.Args[0].List.Cons(context.Args[1])
+	}
 
 	return context.Args[0], nil
 }
 
 func listSize(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
 		return nil, err
 	}
 
-	return runtime.NewNumberValueFromInt64(int64(len(context.Args[0].List))), nil
+	return runtime.NewNumberValueFromInt64(int64(runtime.SequenceLen(context.Args[0]))), nil
 }
 
 func listGet(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.NumberValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue); err != nil {
 		return nil, err
 	}
 
-	size := int64(len(context.Args[0].List))
-	index := context.Args[1].NumberToInt64()
+	size := int64(runtime.SequenceLen(context.Args[0]))
+	index, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
 
 	if index < 0 || index > size-1 {
 		return nil, runtime.NewRuntimeError(context.Pos, "index %d out of bounds (list size is %d)", index, size)
 	}
 
-	return context.Args[0].List[index], nil
+	return runtime.SequenceGet(context.Args[0], int(index)), nil
 }
 
 func listGetKey(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -121,11 +175,11 @@ func listGetKey(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 		return nil, err
 	}
 
-	l := context.Args[0]
+	l := context.Args[0].List.Values()
 
-	for i := 0; i < len(l.List); i++ {
-		if l.List[i].Type == runtime.KeywordValue && l.List[i].Keyword == context.Args[1].Keyword && i+1 < len(l.List) {
-			return l.List[i+1], nil
+	for i := 0; i < len(l); i++ {
+		if l[i].Type == runtime.KeywordValue && l[i].Keyword == context.Args[1].Keyword && i+1 < len(l) {
+			return l[i+1], nil
 		}
 	}
 
@@ -133,18 +187,26 @@ func listGetKey(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 }
 
 func listSet(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.NumberValue, runtime.AnyValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue, runtime.AnyValue); err != nil {
 		return nil, err
 	}
 
-	size := int64(len(context.Args[0].List))
-	index := context.Args[1].NumberToInt64()
+	size := int64(runtime.SequenceLen(context.Args[0]))
+	index, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
 
 	if index < 0 || index > size-1 {
 		return nil, runtime.NewRuntimeError(context.Pos, "index %d out of bounds (list size is %d)", index, size)
 	}
 
-	context.Args[0].List[index] = context.Args[2]
+	if context.Args[0].Type == runtime.VectorValue {
+		context.Args[0].Vector = context.Args[0].Vector.Set(int(index), context.Args[2])
+	} else {
+		context.Args[0].List = context.Args[0].List.Set(int(index), context.Args[2])
+	}
 
 	return context.Args[0], nil
 }
@@ -155,60 +217,73 @@ func listSetKey(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	}
 
 	l := context.Args[0]
+	items := l.List.Values()
 
-	for i := 0; i < len(l.List); i++ {
-		if l.List[i].Type == runtime.KeywordValue && l.List[i].Keyword == context.Args[1].Keyword && i+1 < len(l.List) {
-			l.List[i+1] = context.Args[2]
+	for i := 0; i < len(items); i++ {
+		if items[i].Type == runtime.KeywordValue && items[i].Keyword == context.Args[1].Keyword && i+1 < len(items) {
+			l.List = l.List.Set(i+1, context.Args[2])
 
 			return l, nil
 		}
 	}
 
-	l.List = append(l.List, context.Args[1])
-	l.List = append(l.List, context.Args[2])
+	l.List = l.List.Push(context.Args[1]).Push(context.Args[2])
 
 	return l, nil
 }
 
 func listDrop(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
 		return nil, err
 	}
 
 	l := context.Args[0]
 
-	if len(l.List) == 0 {
-		return nil, runtime.NewRuntimeError(context.Pos, "empty list")
+	if runtime.SequenceLen(l) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "empty %s", l.Type)
 	}
 
-	l.List = l.List[0 : len(l.List)-1]
+	if l.Type == runtime.VectorValue {
+		l.Vector = l.Vector.Pop()
+	} else {
+		l.List = l.List.Pop()
+	}
 
 	return context.Args[0], nil
 }
 
 func listDropLeft(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
 		return nil, err
 	}
 
 	l := context.Args[0]
 
-	if len(l.List) == 0 {
-		return nil, runtime.NewRuntimeError(context.Pos, "empty list")
+	if runtime.SequenceLen(l) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "empty %s", l.Type)
 	}
 
-	l.List = l.List[1:]
+	if l.Type == runtime.VectorValue {
+		l.Vector = l.Vector.Drop(1)
+	} else {
+		l.List = l.List.Rest()
+	}
 
 	return context.Args[0], nil
 }
 
+// listJoin appends the items of the second sequence to the first, the result is of the same kind as the first.
 func listJoin(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.ListValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.SequenceValue); err != nil {
 		return nil, err
 	}
 
-	for _, item := range context.Args[1].List {
-		context.Args[0].List = append(context.Args[0].List, item)
+	if context.Args[0].Type == runtime.VectorValue {
+		for _, item := range runtime.Items(context.Args[1]) {
+			context.Args[0].Vector = context.Args[0].Vector.Push(item)
+		}
+	} else {
+		context.Args[0].List = context.Args[0].List.Append(runtime.NewList(runtime.Items(context.Args[1])))
 	}
 
 	return context.Args[0], nil
@@ -217,78 +292,82 @@ func listJoin(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 func listRange(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	var begin, end int64
 
-	err := runtime.ValidateArguments(context, runtime.ListValue, runtime.NumberValue, runtime.NumberValue)
+	err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue, runtime.NumberValue)
 
 	if err != nil {
-		optionalErr := runtime.ValidateArguments(context, runtime.ListValue, runtime.NumberValue)
+		optionalErr := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue)
 
 		if optionalErr == nil {
-			begin = context.Args[1].NumberToInt64()
-			end = int64(len(context.Args[0].List)) - 1
+			end = int64(runtime.SequenceLen(context.Args[0])) - 1
 		} else {
 			return nil, optionalErr
 		}
 	} else {
-		begin = context.Args[1].NumberToInt64()
-		end = context.Args[2].NumberToInt64()
+		if end, err = runtime.Int64Argument(context, 2); err != nil {
+			return nil, err
+		}
+	}
+
+	if begin, err = runtime.Int64Argument(context, 1); err != nil {
+		return nil, err
 	}
 
-	length := int64(len(context.Args[0].List))
+	length := int64(runtime.SequenceLen(context.Args[0]))
 
 	if begin < 0 || begin > length-1 || begin > end || end < 0 || end > length-1 {
 		return nil, runtime.NewRuntimeError(context.Pos, "invalid bounds %d and %d (list length is %d)", begin, end, length)
 	}
 
-	newList := runtime.NewListValue()
-
-	for _, item := range context.Args[0].List[begin : end+1] {
-		newList.List = append(newList.List, item)
-	}
+	items := runtime.Items(context.Args[0])[begin : end+1]
 
-	if len(newList.List) == 1 {
-		return newList.List[0], nil
+	if len(items) == 1 {
+		return items[0], nil
 	} else {
-		return newList, nil
+		return runtime.NewSequenceOf(context.Args[0], append([]*runtime.Value{}, items...)), nil
 	}
 }
 
 func listReverse(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
 		return nil, err
 	}
 
-	list := context.Args[0].List
-	newList := runtime.NewListValue()
+	list := runtime.Items(context.Args[0])
+	newList := make([]*runtime.Value, 0, len(list))
 
 	for i := len(list) - 1; i >= 0; i-- {
-		newList.List = append(newList.List, list[i])
+		newList = append(newList, list[i])
 	}
 
-	return newList, nil
+	return runtime.NewSequenceOf(context.Args[0], newList), nil
 }
 
 func listRemove(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.ListValue, runtime.NumberValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue); err != nil {
 		return nil, err
 	}
 
-	list := context.Args[0].List
+	list := runtime.Items(context.Args[0])
 	size := int64(len(list))
-	index := context.Args[1].NumberToInt64()
+	index, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
 
 	if index < 0 || index > size-1 {
 		return nil, runtime.NewRuntimeError(context.Pos, "index %d out of bounds (list size is %d)", index, size)
 	}
 
-	newList := runtime.NewListValue()
+	var newList []*runtime.Value
 
 	for i, item := range list {
 		if int64(i) != index {
-			newList.List = append(newList.List, item)
+			newList = append(newList, item)
 		}
 	}
 
-	return newList, nil
+	return runtime.NewSequenceOf(context.Args[0], newList), nil
 }
 
 func listRemoveKey(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -297,13 +376,16 @@ func listRemoveKey(context *runtime.FunctionCallContext) (*runtime.Value, error)
 	}
 
 	l := context.Args[0]
+	items := l.List.Values()
 
-	for i := 0; i < len(l.List); i++ {
-		if l.List[i].Type == runtime.KeywordValue && l.List[i].Keyword == context.Args[1].Keyword && i+1 < len(l.List) {
-			l.List = append(l.List[:i], l.List[i+1:]...)
-			l.List = append(l.List[:i], l.List[i+1:]...)
+	for i := 0; i < len(items); i++ {
+		if items[i].Type == runtime.KeywordValue && items[i].Keyword == context.Args[1].Keyword && i+1 < len(items) {
+			items = append(items[:i], items[i+1:]...)
+			items = append(items[:i], items[i+1:]...)
 		}
 	}
 
+	l.List = runtime.NewList(items)
+
 	return l, nil
 }
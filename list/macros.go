@@ -5,30 +5,61 @@ import (
 	"github.com/raoulvdberge/risp/runtime"
 )
 
+// The macros take an identifier and a body, like (list:map xs x (* x x)). When they're called with a list and
+// a function instead, like (list:map xs square), they call the function with the same name in Symbols.
 var Macros = runtime.Mactab{
-	"filter": runtime.NewMacro(listFilter, true, "any", "identifier", "list"),
-	"map":    runtime.NewMacro(listMap, true, "any", "identifier", "list"),
-	"reduce": runtime.NewMacro(listReduce, true, "any", "identifier", "identifier", "list"),
+	"filter": runtime.NewMacro(listFilter, false, "any", "identifier", "list"),
+	"map":    runtime.NewMacro(listMap, false, "any", "identifier", "list"),
+	"reduce": runtime.NewMacro(listReduce, false, "any", "identifier", "identifier", "list"),
+}
+
+// functionForm checks if a macro is called with a list and a function. If it is, it calls the function with the
+// same name as the macro. Otherwise it validates the nodes against the types of the macro.
+func functionForm(context *runtime.MacroCallContext) (*runtime.Value, bool, error) {
+	if len(context.Nodes) != 2 {
+		return nil, false, runtime.ValidateMacroArguments(context)
+	}
+
+	var args []*runtime.Value
+
+	for _, node := range context.Nodes {
+		arg, err := context.Block.EvalNode(node)
+
+		if err != nil {
+			return nil, true, err
+		}
+
+		args = append(args, arg)
+	}
+
+	fn := context.Block.Scope.GetSymbol(context.Name).Value.Function
+	result, err := fn.Call(context.Block, args, context.Pos)
+
+	return result, true, err
 }
 
 func listFilter(context *runtime.MacroCallContext) (*runtime.Value, error) {
+	if result, ok, err := functionForm(context); ok || err != nil {
+		return result, err
+	}
+
 	list, err := context.Block.EvalNode(context.Nodes[0])
 
 	if err != nil {
 		return nil, err
 	}
 
-	if list.Type != runtime.ListValue {
-		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list")
+	if !runtime.IsSequence(list) {
+		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list or a vector")
 	}
 
 	ident := context.Nodes[1].(*parser.IdentifierNode).Token.Data
 
 	callback := context.Nodes[2]
 
-	filteredList := runtime.NewListValue()
+	var filteredList []*runtime.Value
 
-	for _, item := range list.List {
+	for _, item := range runtime.Items(list) {
 		b := runtime.NewBlock([]parser.Node{callback}, runtime.NewScope(context.Block.Scope))
 		b.Scope.SetSymbolLocally(ident, runtime.NewSymbol(item))
 
@@ -43,31 +74,35 @@ func listFilter(context *runtime.MacroCallContext) (*runtime.Value, error) {
 		}
 
 		if result.Boolean {
-			filteredList.List = append(filteredList.List, item)
+			filteredList = append(filteredList, item)
 		}
 	}
 
-	return filteredList, nil
+	return runtime.NewSequenceOf(list, filteredList), nil
 }
 
 func listMap(context *runtime.MacroCallContext) (*runtime.Value, error) {
+	if result, ok, err := functionForm(context); ok || err != nil {
+		return result, err
+	}
+
 	list, err := context.Block.EvalNode(context.Nodes[0])
 
 	if err != nil {
 		return nil, err
 	}
 
-	if list.Type != runtime.ListValue {
-		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list")
+	if !runtime.IsSequence(list) {
+		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list or a vector")
 	}
 
 	ident := context.Nodes[1].(*parser.IdentifierNode).Token.Data
 
 	callback := context.Nodes[2]
 
-	mappedList := runtime.NewListValue()
+	var mappedList []*runtime.Value
 
-	for _, item := range list.List {
+	for _, item := range runtime.Items(list) {
 		b := runtime.NewBlock([]parser.Node{callback}, runtime.NewScope(context.Block.Scope))
 		b.Scope.SetSymbolLocally(ident, runtime.NewSymbol(item))
 
@@ -77,24 +112,28 @@ func listMap(context *runtime.MacroCallContext) (*runtime.Value, error) {
 			return nil, err
 		}
 
-		mappedList.List = append(mappedList.List, result)
+		mappedList = append(mappedList, result)
 	}
 
-	return mappedList, nil
+	return runtime.NewSequenceOf(list, mappedList), nil
 }
 
 func listReduce(context *runtime.MacroCallContext) (*runtime.Value, error) {
+	if result, ok, err := functionForm(context); ok || err != nil {
+		return result, err
+	}
+
 	list, err := context.Block.EvalNode(context.Nodes[0])
 
 	if err != nil {
 		return nil, err
 	}
 
-	if list.Type != runtime.ListValue {
-		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list")
+	if !runtime.IsSequence(list) {
+		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "expected a list or a vector")
 	}
 
-	if len(list.List) == 0 {
+	if runtime.SequenceLen(list) == 0 {
 		return nil, runtime.NewRuntimeError(context.Nodes[0].Pos(), "empty list")
 	}
 
@@ -103,9 +142,10 @@ func listReduce(context *runtime.MacroCallContext) (*runtime.Value, error) {
 
 	callback := context.Nodes[3]
 
-	reduced := list.List[0]
+	items := runtime.Items(list)
+	reduced := items[0]
 
-	for _, item := range list.List[1:] {
+	for _, item := range items[1:] {
 		b := runtime.NewBlock([]parser.Node{callback}, runtime.NewScope(context.Block.Scope))
 		b.Scope.SetSymbolLocally(identLeft, runtime.NewSymbol(reduced))
 		b.Scope.SetSymbolLocally(identRight, runtime.NewSymbol(item))
@@ -0,0 +1,226 @@
+package list
+
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"sort"
+)
+
+// The functions in this file order values with runtime.Compare, which orders values of any type.
+// All sorting is stable: items that are equal keep their order.
+
+// keyed is an item of a list with the key it's ordered by.
+type keyed struct {
+	item *runtime.Value
+	key  *runtime.Value
+}
+
+// keyedItems returns the items of a list with their keys, which are the items themselves if fn is nil.
+// fn is called once for every item.
+func keyedItems(context *runtime.FunctionCallContext, items []*runtime.Value, fn *runtime.Function) ([]keyed, error) {
+	result := make([]keyed, len(items))
+
+	for i, item := range items {
+		key := item
+
+		if fn != nil {
+			k, err := fn.Call(context.Block, []*runtime.Value{item}, context.Pos)
+
+			if err != nil {
+				return nil, err
+			}
+
+			key = k
+		}
+
+		result[i] = keyed{item: item, key: key}
+	}
+
+	return result, nil
+}
+
+// keyedList returns the items as a list, or as a vector if like is a vector.
+func keyedList(like *runtime.Value, items []keyed) *runtime.Value {
+	var values []*runtime.Value
+
+	for _, item := range items {
+		values = append(values, item.item)
+	}
+
+	return runtime.NewSequenceOf(like, values)
+}
+
+func listSort(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	l := runtime.Items(context.Args[0])
+
+	sort.SliceStable(l, func(i, j int) bool {
+		return runtime.Compare(l[i], l[j]) < 0
+	})
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// listSortBy sorts a list by the result of a key function, which is called once for every item.
+func listSortBy(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	items, err := keyedItems(context, runtime.Items(context.Args[0]), context.Args[1].Function)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return runtime.Compare(items[i].key, items[j].key) < 0
+	})
+
+	return keyedList(context.Args[0], items), nil
+}
+
+// listSortWith sorts a list with a comparator, which is called with two items and returns a negative
+// number if the first item comes first, a positive number if the second item comes first and 0 otherwise.
+func listSortWith(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+		return nil, err
+	}
+
+	l := runtime.Items(context.Args[0])
+
+	var callErr error
+
+	sort.SliceStable(l, func(i, j int) bool {
+		if callErr != nil {
+			return false
+		}
+
+		result, err := context.Args[1].Function.Call(context.Block, []*runtime.Value{l[i], l[j]}, context.Pos)
+
+		if err == nil && result.Type != runtime.NumberValue {
+			err = runtime.NewRuntimeError(context.Pos, "%s: expected the comparator to return a number, got %s", context.Name, result.Type)
+		}
+
+		if err != nil {
+			callErr = err
+
+			return false
+		}
+
+		cmp, _ := runtime.CompareNumbers(result, runtime.NewNumberValueFromInt64(0))
+
+		return cmp < 0
+	})
+
+	if callErr != nil {
+		return nil, callErr
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// listBinarySearch returns the index of a value in a sorted list or vector, or nil if it doesn't contain it.
+func listBinarySearch(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	list, value := context.Args[0], context.Args[1]
+	size := runtime.SequenceLen(list)
+
+	i := sort.Search(size, func(i int) bool {
+		return runtime.Compare(runtime.SequenceGet(list, i), value) >= 0
+	})
+
+	if i < size && runtime.Compare(runtime.SequenceGet(list, i), value) == 0 {
+		return runtime.NewNumberValueFromInt64(int64(i)), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+// listExtreme returns the smallest item of a list for min and min-by, or the largest for max and max-by.
+// The -by variants order the items by the result of a key function. If there are multiple, the first one is returned.
+func listExtreme(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	var fn *runtime.Function
+
+	if context.Name == "min-by" || context.Name == "max-by" {
+		if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.FunctionValue); err != nil {
+			return nil, err
+		}
+
+		fn = context.Args[1].Function
+	} else if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	if runtime.SequenceLen(context.Args[0]) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: the %s is empty", context.Name, context.Args[0].Type)
+	}
+
+	items, err := keyedItems(context, runtime.Items(context.Args[0]), fn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sign := -1
+
+	if context.Name == "max" || context.Name == "max-by" {
+		sign = 1
+	}
+
+	best := items[0]
+
+	for _, item := range items[1:] {
+		if runtime.Compare(item.key, best.key) == sign {
+			best = item
+		}
+	}
+
+	return best.item, nil
+}
+
+// listTopN returns the n largest items of a list, largest first, optionally ordered by the result of a key function.
+func listTopN(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	var fn *runtime.Function
+
+	if len(context.Args) == 3 {
+		if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue, runtime.FunctionValue); err != nil {
+			return nil, err
+		}
+
+		fn = context.Args[2].Function
+	} else if err := runtime.ValidateArguments(context, runtime.SequenceValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	n, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: n can't be negative, got %d", context.Name, n)
+	}
+
+	items, err := keyedItems(context, runtime.Items(context.Args[0]), fn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return runtime.Compare(items[i].key, items[j].key) > 0
+	})
+
+	if n < int64(len(items)) {
+		items = items[:n]
+	}
+
+	return keyedList(context.Args[0], items), nil
+}
@@ -1,17 +1,29 @@
 package main
 
 import (
-	"encoding/json"
+	stdjson "encoding/json"
 	"flag"
 	"fmt"
 	"github.com/raoulvdberge/risp/builtin"
+	"github.com/raoulvdberge/risp/bytes"
+	"github.com/raoulvdberge/risp/crypto"
+	"github.com/raoulvdberge/risp/csv"
+	"github.com/raoulvdberge/risp/encoding"
+	"github.com/raoulvdberge/risp/fs"
+	"github.com/raoulvdberge/risp/json"
+	"github.com/raoulvdberge/risp/lazy"
 	"github.com/raoulvdberge/risp/lexer"
 	"github.com/raoulvdberge/risp/list"
 	"github.com/raoulvdberge/risp/math"
 	"github.com/raoulvdberge/risp/parser"
+	"github.com/raoulvdberge/risp/random"
+	"github.com/raoulvdberge/risp/regex"
 	"github.com/raoulvdberge/risp/repl"
 	"github.com/raoulvdberge/risp/runtime"
+	"github.com/raoulvdberge/risp/set"
 	"github.com/raoulvdberge/risp/strings"
+	"github.com/raoulvdberge/risp/system"
+	"github.com/raoulvdberge/risp/time"
 	"github.com/raoulvdberge/risp/util"
 	"io/ioutil"
 	"os"
@@ -22,15 +34,29 @@ var (
 	runRepl = flag.Bool("repl", false, "runs the repl")
 	ast     = flag.Bool("ast", false, "dumps the abstract syntax tree")
 	debug   = flag.Bool("debug", false, "enabled debug mode")
+	sandbox = flag.String("sandbox", "", "restricts file system access to a directory")
+	code    = flag.String("e", "", "evaluates code instead of a file, leaving stdin to the script")
 )
 
 func main() {
 	flag.Usage = usage
 	flag.Parse()
 
-	if len(flag.Args()) > 0 {
+	if *sandbox != "" {
+		if err := fs.SetSandbox(*sandbox); err != nil {
+			util.ReportError(err, false)
+		}
+	}
+
+	if *code != "" {
+		system.SetArgs(flag.Args())
+
+		run(lexer.NewSourceFromString("<e>", *code))
+	} else if len(flag.Args()) > 0 {
 		var file *util.File
 
+		system.SetArgs(flag.Args()[1:])
+
 		util.Timed("file reading", *debug, func() {
 			os.Chdir(filepath.Dir(flag.Arg(0)))
 
@@ -47,6 +73,8 @@ func main() {
 	} else if *runRepl {
 		s := repl.NewReplSession(apply(runtime.NewBlock(nil, runtime.NewScope(nil))))
 		s.Run()
+
+		util.ReportError(runtime.FlushPorts(), false)
 	} else {
 		bytes, err := ioutil.ReadAll(os.Stdin)
 
@@ -70,7 +98,7 @@ func run(source lexer.Source) {
 	})
 
 	if *ast {
-		bytes, _ := json.MarshalIndent(p, "", "    ")
+		bytes, _ := stdjson.MarshalIndent(p, "", "    ")
 
 		fmt.Println(string(bytes))
 	} else {
@@ -79,6 +107,10 @@ func run(source lexer.Source) {
 		util.Timed("runtime", *debug, func() {
 			_, err := b.Eval()
 
+			if flushErr := runtime.FlushPorts(); err == nil {
+				err = flushErr
+			}
+
 			if err != nil {
 				util.ReportError(err, false)
 			}
@@ -97,11 +129,35 @@ func apply(block *runtime.Block) *runtime.Block {
 
 	block.Scope.ApplySymbols("math", math.Symbols)
 
+	block.Scope.ApplySymbols("regex", regex.Symbols)
+
+	block.Scope.ApplySymbols("fs", fs.Symbols)
+
+	block.Scope.ApplySymbols("os", system.Symbols) // os is a package in Go so we use "system" internally
+
+	block.Scope.ApplySymbols("json", json.Symbols)
+
+	block.Scope.ApplySymbols("csv", csv.Symbols)
+
+	block.Scope.ApplySymbols("time", time.Symbols)
+
+	block.Scope.ApplySymbols("random", random.Symbols)
+
+	block.Scope.ApplySymbols("crypto", crypto.Symbols)
+
+	block.Scope.ApplySymbols("encoding", encoding.Symbols)
+
+	block.Scope.ApplySymbols("bytes", bytes.Symbols)
+
+	block.Scope.ApplySymbols("set", set.Symbols)
+
+	block.Scope.ApplySymbols("lazy", lazy.Symbols)
+
 	return block
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: risp [options] [file ...]\n")
+	fmt.Fprintf(os.Stderr, "usage: risp [options] [file [arguments ...]]\n")
 	fmt.Fprintf(os.Stderr, "Flags:\n")
 	flag.PrintDefaults()
 	os.Exit(2)
@@ -0,0 +1,276 @@
+package math
+
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"math/big"
+)
+
+// maxBits limits the size of the results of shifts and powers so a typo can't allocate gigabytes.
+const maxBits = 1 << 24
+
+// integerArguments validates that all arguments are integral numbers and returns them as integers.
+func integerArguments(context *runtime.FunctionCallContext, types ...runtime.ValueType) ([]*big.Int, error) {
+	if err := runtime.ValidateArguments(context, types...); err != nil {
+		return nil, err
+	}
+
+	var integers []*big.Int
+
+	for i, typ := range types {
+		if typ != runtime.NumberValue {
+			continue
+		}
+
+		n, err := runtime.IntegerArgument(context, i)
+
+		if err != nil {
+			return nil, err
+		}
+
+		integers = append(integers, n)
+	}
+
+	return integers, nil
+}
+
+// mathBitwise implements bit-and, bit-or and bit-xor. Negative numbers behave as infinite two's complement.
+func mathBitwise(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(big.Int)
+
+	switch context.Name {
+	case "bit-and":
+		result.And(n[0], n[1])
+	case "bit-or":
+		result.Or(n[0], n[1])
+	case "bit-xor":
+		result.Xor(n[0], n[1])
+	}
+
+	return runtime.NewNumberValueFromBigInt(result), nil
+}
+
+func mathBitNot(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromBigInt(new(big.Int).Not(n[0])), nil
+}
+
+// mathShift implements shift-left and shift-right. Shifting right is arithmetic, it rounds towards negative infinity.
+func mathShift(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n[1].Sign() < 0 || n[1].Cmp(big.NewInt(maxBits)) > 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: invalid shift amount %s", context.Name, n[1])
+	}
+
+	amount := uint(n[1].Uint64())
+
+	if context.Name == "shift-left" {
+		return runtime.NewNumberValueFromBigInt(new(big.Int).Lsh(n[0], amount)), nil
+	}
+
+	return runtime.NewNumberValueFromBigInt(new(big.Int).Rsh(n[0], amount)), nil
+}
+
+func mathPopcount(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n[0].Sign() < 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: negative numbers have infinitely many set bits", context.Name)
+	}
+
+	count := 0
+
+	for _, word := range n[0].Bits() {
+		for ; word != 0; word &= word - 1 {
+			count++
+		}
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(count)), nil
+}
+
+// mathBitTest returns whether bit i of n is set.
+func mathBitTest(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n[1].Sign() < 0 || n[1].Cmp(big.NewInt(maxBits)) > 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: invalid bit index %s", context.Name, n[1])
+	}
+
+	return runtime.BooleanValueFor(n[0].Bit(int(n[1].Int64())) == 1), nil
+}
+
+// mathBitChange implements bit-set and bit-clear.
+func mathBitChange(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n[1].Sign() < 0 || n[1].Cmp(big.NewInt(maxBits)) > 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: invalid bit index %s", context.Name, n[1])
+	}
+
+	bit := uint(0)
+
+	if context.Name == "bit-set" {
+		bit = 1
+	}
+
+	return runtime.NewNumberValueFromBigInt(new(big.Int).SetBit(n[0], int(n[1].Int64()), bit)), nil
+}
+
+type width struct {
+	bits   uint
+	signed bool
+}
+
+var widths = map[string]width{
+	"i8":  {8, true},
+	"i16": {16, true},
+	"i32": {32, true},
+	"i64": {64, true},
+	"u8":  {8, false},
+	"u16": {16, false},
+	"u32": {32, false},
+	"u64": {64, false},
+}
+
+func widthArgument(context *runtime.FunctionCallContext, i int) (width, error) {
+	w, ok := widths[context.Args[i].Keyword]
+
+	if !ok {
+		return w, runtime.NewRuntimeError(context.Pos, "%s: unknown width %s, expected one of :i8, :i16, :i32, :i64, :u8, :u16, :u32 or :u64", context.Name, context.Args[i])
+	}
+
+	return w, nil
+}
+
+func (w width) min() *big.Int {
+	if !w.signed {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), w.bits-1))
+}
+
+func (w width) max() *big.Int {
+	bits := w.bits
+
+	if w.signed {
+		bits--
+	}
+
+	return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits), big.NewInt(1))
+}
+
+// wrap reduces n modulo 2^bits into the range of the width, like fixed-width integers overflow.
+func (w width) wrap(n *big.Int) *big.Int {
+	modulus := new(big.Int).Lsh(big.NewInt(1), w.bits)
+	result := new(big.Int).Mod(n, modulus)
+
+	if w.signed && result.Cmp(w.max()) > 0 {
+		result.Sub(result, modulus)
+	}
+
+	return result
+}
+
+// saturate clamps n into the range of the width.
+func (w width) saturate(n *big.Int) *big.Int {
+	if n.Cmp(w.min()) < 0 {
+		return w.min()
+	}
+
+	if n.Cmp(w.max()) > 0 {
+		return w.max()
+	}
+
+	return n
+}
+
+// mathFixedWidth implements wrap and saturate, which convert an integer to a fixed width.
+func mathFixedWidth(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue, runtime.KeywordValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := widthArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if context.Name == "wrap" {
+		return runtime.NewNumberValueFromBigInt(w.wrap(n[0])), nil
+	}
+
+	return runtime.NewNumberValueFromBigInt(w.saturate(n[0])), nil
+}
+
+// mathFixedArithmetic implements wrapping-* and saturating-* for add, sub and mul.
+// Both operands should be in the range of the width.
+func mathFixedArithmetic(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	n, err := integerArguments(context, runtime.NumberValue, runtime.NumberValue, runtime.KeywordValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := widthArgument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, operand := range n {
+		if operand.Cmp(w.min()) < 0 || operand.Cmp(w.max()) > 0 {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: argument %d is out of range for %s", context.Name, i+1, context.Args[2])
+		}
+	}
+
+	a, b := n[0], n[1]
+	result := new(big.Int)
+
+	switch context.Name {
+	case "wrapping-add", "saturating-add":
+		result.Add(a, b)
+	case "wrapping-sub", "saturating-sub":
+		result.Sub(a, b)
+	case "wrapping-mul", "saturating-mul":
+		result.Mul(a, b)
+	}
+
+	switch context.Name {
+	case "wrapping-add", "wrapping-sub", "wrapping-mul":
+		return runtime.NewNumberValueFromBigInt(w.wrap(result)), nil
+	default:
+		return runtime.NewNumberValueFromBigInt(w.saturate(result)), nil
+	}
+}
@@ -1,34 +1,170 @@
 package math
 
 import (
+	"github.com/raoulvdberge/risp/lexer"
 	"github.com/raoulvdberge/risp/runtime"
 	"math"
+	"math/big"
+	"strings"
 )
 
 var Symbols = runtime.Symtab{
-	"mod":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathMod, "mod"))),
-	"sqrt":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "sqrt"))),
-	"sin":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "sin"))),
-	"cos":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "cos"))),
-	"tan":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "tan"))),
-	"ceil":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "ceil"))),
-	"floor":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "floor"))),
-	"abs":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "abs"))),
-	"log":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "log"))),
-	"log10":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "log10"))),
-	"pow":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPow, "pow"))),
-	"deg2rad": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathDeg2Rad, "deg2rad"))),
-	"rad2deg": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathRad2Deg, "rad2deg"))),
-	"pi":      runtime.NewSymbol(runtime.NewNumberValueFromFloat64(math.Pi)),
-	"e":       runtime.NewSymbol(runtime.NewNumberValueFromFloat64(math.E)),
-}
-
-func mathMod(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	"mod":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathDivision, "mod"))),
+	"quotient":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathDivision, "quotient"))),
+	"remainder":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathDivision, "remainder"))),
+	"gcd":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathGcd, "gcd"))),
+	"lcm":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathLcm, "lcm"))),
+	"sqrt":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSqrt, "sqrt"))),
+	"sin":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "sin"))),
+	"cos":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "cos"))),
+	"tan":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "tan"))),
+	"ceil":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathRounding, "ceil"))),
+	"floor":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathRounding, "floor"))),
+	"abs":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathRounding, "abs"))),
+	"log":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "log"))),
+	"log10":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathSimpleMath, "log10"))),
+	"pow":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPow, "pow"))),
+	"deg2rad":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathDeg2Rad, "deg2rad"))),
+	"rad2deg":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathRad2Deg, "rad2deg"))),
+	"number?":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "number?"))),
+	"integer?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "integer?"))),
+	"rational?":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "rational?"))),
+	"exact?":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "exact?"))),
+	"inexact?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "inexact?"))),
+	"nan?":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "nan?"))),
+	"infinite?":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPredicate, "infinite?"))),
+	"exact->inexact": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathExactToInexact, "exact->inexact"))),
+	"inexact->exact": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathInexactToExact, "inexact->exact"))),
+	"rationalize":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathRationalize, "rationalize"))),
+	"string->number": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathStringToNumber, "string->number"))),
+	"number->string": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathNumberToString, "number->string"))),
+	"bit-and":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitwise, "bit-and"))),
+	"bit-or":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitwise, "bit-or"))),
+	"bit-xor":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitwise, "bit-xor"))),
+	"bit-not":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitNot, "bit-not"))),
+	"shift-left":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathShift, "shift-left"))),
+	"shift-right":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathShift, "shift-right"))),
+	"popcount":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathPopcount, "popcount"))),
+	"bit-test":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitTest, "bit-test"))),
+	"bit-set":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitChange, "bit-set"))),
+	"bit-clear":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathBitChange, "bit-clear"))),
+	"wrap":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedWidth, "wrap"))),
+	"saturate":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedWidth, "saturate"))),
+	"wrapping-add":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedArithmetic, "wrapping-add"))),
+	"wrapping-sub":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedArithmetic, "wrapping-sub"))),
+	"wrapping-mul":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedArithmetic, "wrapping-mul"))),
+	"saturating-add": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedArithmetic, "saturating-add"))),
+	"saturating-sub": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedArithmetic, "saturating-sub"))),
+	"saturating-mul": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(mathFixedArithmetic, "saturating-mul"))),
+	"pi":             runtime.NewSymbol(runtime.NewNumberValueFromFloat64(math.Pi)),
+	"e":              runtime.NewSymbol(runtime.NewNumberValueFromFloat64(math.E)),
+}
+
+// mathDivision implements quotient (truncated towards zero), remainder (sign of the dividend)
+// and mod (sign of the divisor). The results are exact for exact numbers, also non-integral ones.
+func mathDivision(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	if !context.Args[0].NumberIsExact() || !context.Args[1].NumberIsExact() {
+		return inexactDivision(context)
+	}
+
+	n1 := context.Args[0].Number
+	n2 := context.Args[1].Number
+
+	if n2.Sign() == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "division by zero")
+	}
+
+	ratio := new(big.Rat).Quo(n1, n2)
+	quotient := new(big.Int).Quo(ratio.Num(), ratio.Denom())
+
+	if context.Name == "quotient" {
+		return runtime.NewNumberValueFromBigInt(quotient), nil
+	}
+
+	remainder := new(big.Rat).Sub(n1, new(big.Rat).Mul(n2, new(big.Rat).SetInt(quotient)))
+
+	if context.Name == "mod" && remainder.Sign() != 0 && remainder.Sign() != n2.Sign() {
+		remainder.Add(remainder, n2)
+	}
+
+	return runtime.NewNumberValueFromRat(remainder), nil
+}
+
+func inexactDivision(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	x := context.Args[0].NumberToFloat64()
+	y := context.Args[1].NumberToFloat64()
+
+	if y == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "division by zero")
+	}
+
+	if context.Name == "quotient" {
+		return runtime.NewNumberValueFromFloat64(math.Trunc(x / y)), nil
+	}
+
+	remainder := math.Mod(x, y)
+
+	if context.Name == "mod" && remainder != 0 && (remainder < 0) != (y < 0) {
+		remainder += y
+	}
+
+	return runtime.NewNumberValueFromFloat64(remainder), nil
+}
+
+func mathGcd(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	a, err := runtime.IntegerArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := runtime.IntegerArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromBigInt(gcd(a, b)), nil
+}
+
+func mathLcm(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
 		return nil, err
 	}
 
-	return runtime.NewNumberValueFromInt64(context.Args[0].NumberToInt64() % context.Args[1].NumberToInt64()), nil
+	a, err := runtime.IntegerArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := runtime.IntegerArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Sign() == 0 || b.Sign() == 0 {
+		return runtime.NewNumberValueFromInt64(0), nil
+	}
+
+	lcm := new(big.Int).Mul(a, b)
+	lcm.Abs(lcm)
+	lcm.Quo(lcm, gcd(a, b))
+
+	return runtime.NewNumberValueFromBigInt(lcm), nil
+}
+
+func gcd(a *big.Int, b *big.Int) *big.Int {
+	return new(big.Int).GCD(nil, nil, new(big.Int).Abs(a), new(big.Int).Abs(b))
 }
 
 func mathSimpleMath(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -39,20 +175,12 @@ func mathSimpleMath(context *runtime.FunctionCallContext) (*runtime.Value, error
 	var callback func(float64) float64
 
 	switch context.Name {
-	case "sqrt":
-		callback = math.Sqrt
 	case "sin":
 		callback = math.Sin
 	case "cos":
 		callback = math.Cos
 	case "tan":
 		callback = math.Tan
-	case "ceil":
-		callback = math.Ceil
-	case "floor":
-		callback = math.Floor
-	case "abs":
-		callback = math.Abs
 	case "log":
 		callback = math.Log
 	case "log10":
@@ -62,12 +190,176 @@ func mathSimpleMath(context *runtime.FunctionCallContext) (*runtime.Value, error
 	return runtime.NewNumberValueFromFloat64(callback(context.Args[0].NumberToFloat64())), nil
 }
 
+// mathSqrt returns an exact result for exact perfect squares, and an inexact one otherwise.
+func mathSqrt(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	n := context.Args[0]
+
+	switch n.NumberKind {
+	case runtime.ExactNumber:
+		if n.Number.Sign() >= 0 {
+			num := new(big.Int).Sqrt(n.Number.Num())
+			denom := new(big.Int).Sqrt(n.Number.Denom())
+
+			root := new(big.Rat).SetFrac(num, denom)
+
+			if new(big.Rat).Mul(root, root).Cmp(n.Number) == 0 {
+				return runtime.NewNumberValueFromRat(root), nil
+			}
+		}
+	case runtime.BigFloatNumber:
+		if n.BigFloat.Sign() < 0 {
+			return nil, runtime.NewRuntimeError(context.Pos, "square root of negative number %s", n)
+		}
+
+		return runtime.NewNumberValueFromBigFloat(new(big.Float).SetPrec(n.BigFloat.Prec()).Sqrt(n.BigFloat)), nil
+	}
+
+	return runtime.NewNumberValueFromFloat64(math.Sqrt(n.NumberToFloat64())), nil
+}
+
+// mathRounding implements ceil, floor and abs. Exact numbers stay exact.
+func mathRounding(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	switch context.Args[0].NumberKind {
+	case runtime.FloatNumber:
+		var callback func(float64) float64
+
+		switch context.Name {
+		case "abs":
+			callback = math.Abs
+		case "floor":
+			callback = math.Floor
+		case "ceil":
+			callback = math.Ceil
+		}
+
+		return runtime.NewNumberValueFromFloat64(callback(context.Args[0].Float)), nil
+	case runtime.BigFloatNumber:
+		return bigFloatRounding(context.Name, context.Args[0].BigFloat), nil
+	}
+
+	n := context.Args[0].Number
+
+	if context.Name == "abs" {
+		return runtime.NewNumberValueFromRat(new(big.Rat).Abs(n)), nil
+	}
+
+	// the denominator is always positive, so Euclidean division rounds towards negative infinity
+	floor, m := new(big.Int).DivMod(n.Num(), n.Denom(), new(big.Int))
+
+	if context.Name == "ceil" && m.Sign() != 0 {
+		floor.Add(floor, big.NewInt(1))
+	}
+
+	return runtime.NewNumberValueFromBigInt(floor), nil
+}
+
+func bigFloatRounding(name string, f *big.Float) *runtime.Value {
+	result := new(big.Float).SetPrec(f.Prec())
+
+	if name == "abs" {
+		return runtime.NewNumberValueFromBigFloat(result.Abs(f))
+	}
+
+	if f.IsInf() {
+		return runtime.NewNumberValueFromBigFloat(result.Set(f))
+	}
+
+	truncated, accuracy := f.Int(nil)
+
+	if name == "floor" && accuracy == big.Above {
+		truncated.Sub(truncated, big.NewInt(1))
+	} else if name == "ceil" && accuracy == big.Below {
+		truncated.Add(truncated, big.NewInt(1))
+	}
+
+	return runtime.NewNumberValueFromBigFloat(result.SetInt(truncated))
+}
+
+// mathPow is exact if the base is exact and the exponent is an exact integer.
 func mathPow(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
 		return nil, err
 	}
 
-	return runtime.NewNumberValueFromFloat64(math.Pow(context.Args[0].NumberToFloat64(), context.Args[1].NumberToFloat64())), nil
+	if !context.Args[0].NumberIsExact() || !context.Args[1].NumberIsExact() || !context.Args[1].NumberIsInteger() {
+		if context.Args[0].NumberKind == runtime.BigFloatNumber && context.Args[1].NumberIsInteger() {
+			return bigFloatPow(context)
+		}
+
+		return runtime.NewNumberValueFromFloat64(math.Pow(context.Args[0].NumberToFloat64(), context.Args[1].NumberToFloat64())), nil
+	}
+
+	base := context.Args[0].Number
+	exponent := context.Args[1].Number
+
+	if base.Sign() == 0 && exponent.Sign() < 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "division by zero")
+	}
+
+	abs := new(big.Int).Abs(exponent.Num())
+
+	// the result has about bits*abs bits, bits is 0 for 0, 1 and -1, whose powers are small
+	bits := max(base.Num().BitLen(), base.Denom().BitLen()) - 1
+
+	if bits > 0 && (abs.BitLen() > 32 || int64(bits)*abs.Int64() > maxBits) {
+		return nil, runtime.NewRuntimeError(context.Pos, "exponent %s is too large", exponent.Num())
+	}
+
+	num := new(big.Int).Exp(base.Num(), abs, nil)
+	denom := new(big.Int).Exp(base.Denom(), abs, nil)
+
+	if exponent.Sign() < 0 {
+		num, denom = denom, num
+	}
+
+	return runtime.NewNumberValueFromRat(new(big.Rat).SetFrac(num, denom)), nil
+}
+
+// bigFloatPow raises an arbitrary precision float to an integral power by repeated squaring.
+func bigFloatPow(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	base := context.Args[0].BigFloat
+	exponent, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	negative := exponent < 0
+
+	if negative {
+		exponent = -exponent
+	}
+
+	result := new(big.Float).SetPrec(base.Prec()).SetInt64(1)
+	square := new(big.Float).SetPrec(base.Prec()).Set(base)
+
+	err = runtime.BigFloatOperation(func() {
+		for ; exponent > 0; exponent >>= 1 {
+			if exponent&1 == 1 {
+				result.Mul(result, square)
+			}
+
+			square.Mul(square, square)
+		}
+
+		if negative {
+			result.Quo(new(big.Float).SetPrec(base.Prec()).SetInt64(1), result)
+		}
+	})
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s", err.Error())
+	}
+
+	return runtime.NewNumberValueFromBigFloat(result), nil
 }
 
 func mathDeg2Rad(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -85,3 +377,286 @@ func mathRad2Deg(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 
 	return runtime.NewNumberValueFromFloat64((context.Args[0].NumberToFloat64() * 180) / math.Pi), nil
 }
+
+// mathPredicate implements the numeric predicates. They accept any value, but only hold for numbers.
+func mathPredicate(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	n := context.Args[0]
+
+	if n.Type != runtime.NumberValue {
+		return runtime.False, nil
+	}
+
+	result := false
+
+	switch context.Name {
+	case "number?":
+		result = true
+	case "integer?":
+		result = n.NumberIsInteger()
+	case "rational?":
+		result = n.NumberIsFinite() && !n.NumberIsNaN()
+	case "exact?":
+		result = n.NumberIsExact()
+	case "inexact?":
+		result = !n.NumberIsExact()
+	case "nan?":
+		result = n.NumberIsNaN()
+	case "infinite?":
+		result = !n.NumberIsFinite() && !n.NumberIsNaN()
+	}
+
+	return runtime.BooleanValueFor(result), nil
+}
+
+// mathExactToInexact converts a number to a float64, or to an arbitrary precision float
+// if a precision in bits is given.
+func mathExactToInexact(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err == nil {
+		return runtime.NewNumberValueFromFloat64(context.Args[0].NumberToFloat64()), nil
+	}
+
+	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	prec, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if prec < 1 || prec > big.MaxPrec {
+		return nil, runtime.NewRuntimeError(context.Pos, "invalid precision %d", prec)
+	}
+
+	n := context.Args[0]
+
+	if n.NumberIsNaN() {
+		return nil, runtime.NewRuntimeError(context.Pos, "NaN has no arbitrary precision representation")
+	}
+
+	f := new(big.Float).SetPrec(uint(prec))
+
+	switch n.NumberKind {
+	case runtime.ExactNumber:
+		f.SetRat(n.Number)
+	case runtime.FloatNumber:
+		f.SetFloat64(n.Float)
+	case runtime.BigFloatNumber:
+		f.Set(n.BigFloat)
+	}
+
+	return runtime.NewNumberValueFromBigFloat(f), nil
+}
+
+func mathInexactToExact(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	r, err := context.Args[0].NumberToRat()
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s", err.Error())
+	}
+
+	return runtime.NewNumberValueFromRat(r), nil
+}
+
+// mathRationalize returns the simplest rational number that differs from the first argument
+// by no more than the second argument. The result is inexact if one of the arguments is.
+func mathRationalize(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	x, err := context.Args[0].NumberToRat()
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s", err.Error())
+	}
+
+	y, err := context.Args[1].NumberToRat()
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s", err.Error())
+	}
+
+	y = new(big.Rat).Abs(y)
+
+	result := simplestRational(new(big.Rat).Sub(x, y), new(big.Rat).Add(x, y))
+
+	if !context.Args[0].NumberIsExact() || !context.Args[1].NumberIsExact() {
+		f, _ := result.Float64()
+
+		return runtime.NewNumberValueFromFloat64(f), nil
+	}
+
+	return runtime.NewNumberValueFromRat(result), nil
+}
+
+// simplestRational returns the rational with the smallest denominator in [low, high].
+func simplestRational(low *big.Rat, high *big.Rat) *big.Rat {
+	switch {
+	case low.Sign() > 0:
+		return simplestPositiveRational(low, high)
+	case high.Sign() < 0:
+		r := simplestPositiveRational(new(big.Rat).Neg(high), new(big.Rat).Neg(low))
+
+		return r.Neg(r)
+	default:
+		return new(big.Rat)
+	}
+}
+
+func simplestPositiveRational(low *big.Rat, high *big.Rat) *big.Rat {
+	if low.IsInt() {
+		return low
+	}
+
+	floor := new(big.Int).Quo(low.Num(), low.Denom())
+
+	if floor.Cmp(new(big.Int).Quo(high.Num(), high.Denom())) < 0 {
+		return new(big.Rat).SetInt(floor.Add(floor, big.NewInt(1)))
+	}
+
+	integral := new(big.Rat).SetInt(floor)
+
+	rest := simplestPositiveRational(
+		new(big.Rat).Inv(new(big.Rat).Sub(high, integral)),
+		new(big.Rat).Inv(new(big.Rat).Sub(low, integral)),
+	)
+
+	return integral.Add(integral, rest.Inv(rest))
+}
+
+// mathStringToNumber parses a string with the number literal syntax, or an integer or fraction in the given radix.
+func mathStringToNumber(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	radix := int64(10)
+
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+
+		if radix, err = runtime.Int64Argument(context, 1); err != nil {
+			return nil, err
+		}
+
+		if radix < 2 || radix > 36 {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: invalid radix %d", context.Name, radix)
+		}
+	}
+
+	s := strings.TrimSpace(context.Args[0].Str)
+
+	if radix != 10 {
+		number, err := runtime.ParseNumberInRadix(s, int(radix))
+
+		if err != nil {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
+		}
+
+		return runtime.NewNumberValueFromRat(number), nil
+	}
+
+	l := lexer.NewLexer(lexer.NewSourceFromString(context.Name, s))
+
+	if err := l.Lex(); err != nil {
+		if syntaxErr, ok := err.(*lexer.SyntaxError); ok {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: %s at position %d", context.Name, syntaxErr.Message(), syntaxErr.Pos().Col)
+		}
+
+		return nil, err
+	}
+
+	if len(l.Tokens) != 1 || l.Tokens[0].Type != lexer.Number {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: '%s' is not a number", context.Name, context.Args[0].Str)
+	}
+
+	number, err := runtime.NewNumberValueFromString(l.Tokens[0].Data)
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
+	}
+
+	return number, nil
+}
+
+// mathNumberToString formats a number. It takes options as keyword arguments:
+// :radix, :notation (:fixed, :scientific or :rational), :precision and :separator.
+// A precision without a notation implies fixed notation.
+func mathNumberToString(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 || context.Args[0].Type != runtime.NumberValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a number", context.Name)
+	}
+
+	options := context.Args[1:]
+
+	if len(options)%2 != 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected options as keyword and value pairs", context.Name)
+	}
+
+	format := runtime.NewNumberFormat()
+
+	for i := 0; i < len(options); i += 2 {
+		key, value := options[i], options[i+1]
+
+		if key.Type != runtime.KeywordValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a keyword, got %s", context.Name, key.Type)
+		}
+
+		switch key.Keyword {
+		case "radix", "precision":
+			if value.Type != runtime.NumberValue || !value.NumberIsInteger() {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be an integer", context.Name, key)
+			}
+
+			n := value.NumberToInt64()
+
+			if key.Keyword == "radix" {
+				if n < 2 || n > 36 {
+					return nil, runtime.NewRuntimeError(context.Pos, "%s: invalid radix %d", context.Name, n)
+				}
+
+				format.Radix = int(n)
+			} else {
+				if n < 0 || n > 10000 {
+					return nil, runtime.NewRuntimeError(context.Pos, "%s: invalid precision %d", context.Name, n)
+				}
+
+				format.Precision = int(n)
+
+				if format.Notation == "" {
+					format.Notation = "fixed"
+				}
+			}
+		case "notation":
+			if value.Type != runtime.KeywordValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a keyword", context.Name, key)
+			}
+
+			format.Notation = value.Keyword
+		case "separator":
+			if value.Type != runtime.StringValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a string", context.Name, key)
+			}
+
+			format.Separator = value.Str
+		default:
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown option %s", context.Name, key)
+		}
+	}
+
+	s, err := runtime.FormatNumber(context.Args[0], format)
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
+	}
+
+	return runtime.NewStringValue(s), nil
+}
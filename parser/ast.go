@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"encoding/hex"
 	"github.com/raoulvdberge/risp/lexer"
+	"unicode/utf8"
 )
 
 type Node interface {
@@ -74,6 +76,100 @@ func (n *KeywordNode) String() string {
 	return n.Token.Data
 }
 
+type CharNode struct {
+	Token *lexer.Token `json:"token"`
+}
+
+func (n *CharNode) Name() string {
+	return "char"
+}
+
+func (n *CharNode) Pos() *lexer.TokenPos {
+	return n.Token.Pos
+}
+
+func (n *CharNode) String() string {
+	r, _ := utf8.DecodeRuneInString(n.Token.Data)
+
+	return "#\\" + lexer.CharName(r)
+}
+
+type BytesNode struct {
+	Token *lexer.Token `json:"token"`
+}
+
+func (n *BytesNode) Name() string {
+	return "bytes"
+}
+
+func (n *BytesNode) Pos() *lexer.TokenPos {
+	return n.Token.Pos
+}
+
+func (n *BytesNode) String() string {
+	return "#x\"" + hex.EncodeToString([]byte(n.Token.Data)) + "\""
+}
+
+type SetNode struct {
+	OpenToken  *lexer.Token `json:"open"`
+	CloseToken *lexer.Token `json:"close"`
+	Nodes      []Node       `json:"nodes"`
+}
+
+func (n *SetNode) Name() string {
+	return "set"
+}
+
+func (n *SetNode) Pos() *lexer.TokenPos {
+	return n.OpenToken.Pos
+}
+
+func (n *SetNode) String() string {
+	s := "#{"
+
+	for i, elem := range n.Nodes {
+		s += elem.String()
+
+		if i != len(n.Nodes)-1 {
+			s += " "
+		}
+	}
+
+	s += "}"
+
+	return s
+}
+
+type VectorNode struct {
+	OpenToken  *lexer.Token `json:"open"`
+	CloseToken *lexer.Token `json:"close"`
+	Nodes      []Node       `json:"nodes"`
+}
+
+func (n *VectorNode) Name() string {
+	return "vector"
+}
+
+func (n *VectorNode) Pos() *lexer.TokenPos {
+	return n.OpenToken.Pos
+}
+
+func (n *VectorNode) String() string {
+	s := "["
+
+	for i, elem := range n.Nodes {
+		s += elem.String()
+
+		if i != len(n.Nodes)-1 {
+			s += " "
+		}
+	}
+
+	s += "]"
+
+	return s
+}
+
 type ListNode struct {
 	OpenToken  *lexer.Token `json:"open"`
 	CloseToken *lexer.Token `json:"close"`
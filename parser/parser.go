@@ -59,12 +59,20 @@ func (p *Parser) nextNode() (Node, error) {
 	case t.IsType(lexer.String):
 		node = &StringNode{Token: t}
 
+		p.next()
+	case t.IsType(lexer.Char):
+		node = &CharNode{Token: t}
+
+		p.next()
+	case t.IsType(lexer.Bytes):
+		node = &BytesNode{Token: t}
+
 		p.next()
 	case t.IsTypeAndData(lexer.Separator, "'"):
 		p.next()
 
 		if p.isEOF() {
-			return nil, lexer.NewSyntaxError(p.last().Pos, "expected something to quote")
+			return nil, lexer.NewIncompleteSyntaxError(p.last().Pos, "expected something to quote")
 		}
 
 		quotedNode, err := p.nextNode()
@@ -75,52 +83,79 @@ func (p *Parser) nextNode() (Node, error) {
 
 		node = &QuoteNode{Token: t, Node: quotedNode}
 	case t.IsTypeAndData(lexer.Separator, "("):
-		listNode := &ListNode{
-			OpenToken: t,
+		nodes, closeToken, err := p.parseEnclosed("list", ")")
+
+		if err != nil {
+			return nil, err
 		}
 
-		depth := 1
+		node = &ListNode{OpenToken: t, CloseToken: closeToken, Nodes: nodes}
+	case t.IsTypeAndData(lexer.Separator, "#{"):
+		nodes, closeToken, err := p.parseEnclosed("set", "}")
 
-		p.next()
+		if err != nil {
+			return nil, err
+		}
 
-		var tokens []*lexer.Token
+		node = &SetNode{OpenToken: t, CloseToken: closeToken, Nodes: nodes}
+	case t.IsTypeAndData(lexer.Separator, "["):
+		nodes, closeToken, err := p.parseEnclosed("vector", "]")
 
-		for p.hasNext() {
-			depth += p.current().DepthModifier()
+		if err != nil {
+			return nil, err
+		}
 
-			if depth == 0 {
-				listNode.CloseToken = p.current()
+		node = &VectorNode{OpenToken: t, CloseToken: closeToken, Nodes: nodes}
+	default:
+		return nil, lexer.NewSyntaxError(t.Pos, "unexpected token '%s'", t.Data)
+	}
 
-				p.next()
+	return node, nil
+}
 
-				break
-			}
+// parseEnclosed parses the nodes between the current opening token and its closing token, which should be close.
+func (p *Parser) parseEnclosed(name string, close string) ([]Node, *lexer.Token, error) {
+	var closeToken *lexer.Token
 
-			tokens = append(tokens, p.current())
+	depth := 1
+
+	p.next()
+
+	var tokens []*lexer.Token
+
+	for p.hasNext() {
+		depth += p.current().DepthModifier()
+
+		if depth == 0 {
+			closeToken = p.current()
 
 			p.next()
-		}
 
-		if depth != 0 {
-			return nil, lexer.NewSyntaxError(p.last().Pos, "unclosed list")
+			break
 		}
 
-		parser := NewParser(tokens)
+		tokens = append(tokens, p.current())
 
-		err := parser.Parse()
+		p.next()
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	if depth != 0 {
+		return nil, nil, lexer.NewIncompleteSyntaxError(p.last().Pos, "unclosed %s", name)
+	}
 
-		listNode.Nodes = parser.Nodes
+	if closeToken.Data != close {
+		return nil, nil, lexer.NewSyntaxError(closeToken.Pos, "expected '%s' to close %s, got '%s'", close, name, closeToken.Data)
+	}
 
-		node = listNode
-	default:
-		return nil, lexer.NewSyntaxError(t.Pos, "unexpected token '%s'", t.Data)
+	parser := NewParser(tokens)
+
+	err := parser.Parse()
+
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return node, nil
+	return parser.Nodes, closeToken, nil
 }
 
 func (p *Parser) Parse() error {
@@ -0,0 +1,361 @@
+package random
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/raoulvdberge/risp/runtime"
+	"math/big"
+	"math/rand"
+)
+
+// Every function takes an optional generator as its last argument. Without one, the global generator is used,
+// which is randomly seeded at startup and can be seeded with seed to make a script reproducible.
+// secure is a generator backed by crypto/rand, for tokens and other values that must be unpredictable.
+// uuid uses secure without a generator, since UUIDs are often used as identifiers that shouldn't be guessed.
+var Symbols = runtime.Symtab{
+	"generator":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomGenerator, "generator"))),
+	"generator?":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomIsGenerator, "generator?"))),
+	"secure":      runtime.NewSymbol(runtime.NewGeneratorValue(secure)),
+	"seed":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomSeed, "seed"))),
+	"int":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomInt, "int"))),
+	"float":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomFloat, "float"))),
+	"normal":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomNormal, "normal"))),
+	"exponential": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomExponential, "exponential"))),
+	"shuffle":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomShuffle, "shuffle"))),
+	"choice":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomChoice, "choice"))),
+	"sample":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomSample, "sample"))),
+	"uuid":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(randomUuid, "uuid"))),
+}
+
+var global = rand.New(rand.NewSource(cryptoSeed()))
+
+var secure = rand.New(secureSource{})
+
+// secureSource reads from crypto/rand, seeding it has no effect.
+type secureSource struct{}
+
+func (secureSource) Uint64() uint64 {
+	var b [8]byte
+
+	cryptorand.Read(b[:])
+
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func (s secureSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func (secureSource) Seed(int64) {
+}
+
+func cryptoSeed() int64 {
+	return secureSource{}.Int63()
+}
+
+// newGenerator returns a generator that produces the same values for the same seed on every platform.
+func newGenerator(seed *big.Int) *rand.Rand {
+	// use the lowest 64 bits, in two's complement for negative seeds
+	s := new(big.Int).And(seed, new(big.Int).SetUint64(1<<64-1)).Uint64()
+
+	return rand.New(rand.NewSource(int64(s)))
+}
+
+// generatorArguments validates arguments of the given types, optionally followed by a generator.
+// It returns that generator, or the global generator if it's omitted.
+func generatorArguments(context *runtime.FunctionCallContext, types ...runtime.ValueType) (*rand.Rand, error) {
+	if len(context.Args) == len(types)+1 {
+		if err := runtime.ValidateArguments(context, append(types, runtime.GeneratorValue)...); err != nil {
+			return nil, err
+		}
+
+		return context.Args[len(types)].Generator, nil
+	}
+
+	if err := runtime.ValidateArguments(context, types...); err != nil {
+		return nil, err
+	}
+
+	return global, nil
+}
+
+// argumentCount returns the number of arguments without the optional generator.
+func argumentCount(context *runtime.FunctionCallContext) int {
+	n := len(context.Args)
+
+	if n > 0 && context.Args[n-1].Type == runtime.GeneratorValue {
+		return n - 1
+	}
+
+	return n
+}
+
+// randomGenerator returns a new generator, seeded with an integer or randomly without one.
+func randomGenerator(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) == 0 {
+		return runtime.NewGeneratorValue(rand.New(rand.NewSource(cryptoSeed()))), nil
+	}
+
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	seed, err := runtime.IntegerArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewGeneratorValue(newGenerator(seed)), nil
+}
+
+func randomIsGenerator(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.GeneratorValue), nil
+}
+
+// randomSeed seeds the global generator with an integer.
+func randomSeed(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	seed, err := runtime.IntegerArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	global = newGenerator(seed)
+
+	return runtime.Nil, nil
+}
+
+// randomInt returns a uniformly distributed integer between low and high, inclusive. Like list:seq, the bounds may be of any size.
+func randomInt(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	r, err := generatorArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	low, err := runtime.IntegerArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	high, err := runtime.IntegerArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if low.Cmp(high) > 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: low can't be higher than high (%s > %s)", context.Name, low, high)
+	}
+
+	span := new(big.Int).Sub(high, low)
+	span.Add(span, big.NewInt(1))
+
+	return runtime.NewNumberValueFromBigInt(new(big.Int).Add(low, below(r, span))), nil
+}
+
+// below returns a uniformly distributed integer in [0, n).
+func below(r *rand.Rand, n *big.Int) *big.Int {
+	if n.IsInt64() {
+		return big.NewInt(r.Int63n(n.Int64()))
+	}
+
+	words := (n.BitLen() + 63) / 64
+
+	// generate as many bits as n has and retry if the result is too large, which happens less than half of the time
+	for {
+		x := new(big.Int)
+
+		for i := 0; i < words; i++ {
+			x.Lsh(x, 64)
+			x.Or(x, new(big.Int).SetUint64(r.Uint64()))
+		}
+
+		x.Rsh(x, uint(words*64-n.BitLen()))
+
+		if x.Cmp(n) < 0 {
+			return x
+		}
+	}
+}
+
+// randomFloat returns a uniformly distributed float in [0, 1), or in [low, high) if they are given.
+func randomFloat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if argumentCount(context) == 0 {
+		r, err := generatorArguments(context)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return runtime.NewNumberValueFromFloat64(r.Float64()), nil
+	}
+
+	r, err := generatorArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	low, high := context.Args[0].NumberToFloat64(), context.Args[1].NumberToFloat64()
+
+	if low > high {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: low can't be higher than high (%s > %s)", context.Name, context.Args[0], context.Args[1])
+	}
+
+	return runtime.NewNumberValueFromFloat64(low + (high-low)*r.Float64()), nil
+}
+
+// randomNormal returns a normally distributed float, with a mean of 0 and a standard deviation of 1 unless they are given.
+func randomNormal(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if argumentCount(context) == 0 {
+		r, err := generatorArguments(context)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return runtime.NewNumberValueFromFloat64(r.NormFloat64()), nil
+	}
+
+	r, err := generatorArguments(context, runtime.NumberValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mean, stddev := context.Args[0].NumberToFloat64(), context.Args[1].NumberToFloat64()
+
+	if stddev < 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: the standard deviation can't be negative, got %s", context.Name, context.Args[1])
+	}
+
+	return runtime.NewNumberValueFromFloat64(mean + stddev*r.NormFloat64()), nil
+}
+
+// randomExponential returns an exponentially distributed float, with a rate of 1 unless it's given.
+func randomExponential(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if argumentCount(context) == 0 {
+		r, err := generatorArguments(context)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return runtime.NewNumberValueFromFloat64(r.ExpFloat64()), nil
+	}
+
+	r, err := generatorArguments(context, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rate := context.Args[0].NumberToFloat64()
+
+	if !(rate > 0) {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: the rate should be positive, got %s", context.Name, context.Args[0])
+	}
+
+	return runtime.NewNumberValueFromFloat64(r.ExpFloat64() / rate), nil
+}
+
+// randomShuffle returns the items of a list or vector in a random order, in a sequence of the same kind.
+func randomShuffle(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	r, err := generatorArguments(context, runtime.SequenceValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l := runtime.Items(context.Args[0])
+
+	r.Shuffle(len(l), func(i, j int) {
+		l[i], l[j] = l[j], l[i]
+	})
+
+	return runtime.NewSequenceOf(context.Args[0], l), nil
+}
+
+// randomChoice returns a random item of a non-empty list or vector.
+func randomChoice(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	r, err := generatorArguments(context, runtime.SequenceValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := runtime.Items(context.Args[0])
+
+	if len(items) == 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: cannot choose from an empty list", context.Name)
+	}
+
+	return items[r.Intn(len(items))], nil
+}
+
+// randomSample returns n items of a list or vector, chosen without replacement, in a random order.
+func randomSample(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	r, err := generatorArguments(context, runtime.SequenceValue, runtime.NumberValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := runtime.Items(context.Args[0])
+
+	if n < 0 || n > int64(len(items)) {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: cannot take a sample of %d items from a list of %d items", context.Name, n, len(items))
+	}
+
+	// a partial Fisher-Yates shuffle
+	for i := 0; i < int(n); i++ {
+		j := i + r.Intn(len(items)-i)
+
+		items[i], items[j] = items[j], items[i]
+	}
+
+	return runtime.NewSequenceOf(context.Args[0], items[:n]), nil
+}
+
+// randomUuid returns a version 4 UUID from the secure generator, or from the given generator to make it reproducible.
+func randomUuid(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	r := secure
+
+	if len(context.Args) > 0 {
+		generator, err := generatorArguments(context)
+
+		if err != nil {
+			return nil, err
+		}
+
+		r = generator
+	}
+
+	var b [16]byte
+
+	binary.BigEndian.PutUint64(b[:8], r.Uint64())
+	binary.BigEndian.PutUint64(b[8:], r.Uint64())
+
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	return runtime.NewStringValue(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])), nil
+}
@@ -0,0 +1,337 @@
+package regex
+
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"regexp"
+	"sync"
+)
+
+var Symbols = runtime.Symtab{
+	"compile":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexCompile, "compile"))),
+	"quote":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexQuote, "quote"))),
+	"match?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexMatch, "match?"))),
+	"find":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexFind, "find"))),
+	"find-all":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexFindAll, "find-all"))),
+	"submatch":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexSubmatch, "submatch"))),
+	"submatch-all": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexSubmatchAll, "submatch-all"))),
+	"named":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexNamed, "named"))),
+	"replace":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexReplace, "replace"))),
+	"split":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(regexSplit, "split"))),
+}
+
+// maxCacheSize is the amount of patterns that are kept compiled, the cache is emptied when it's full.
+const maxCacheSize = 256
+
+var (
+	cache      = make(map[string]*regexp.Regexp)
+	cacheMutex sync.Mutex
+)
+
+// compile compiles a pattern, or returns it from the cache if it was compiled before.
+func compile(pattern string) (*regexp.Regexp, error) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if re, ok := cache[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cache) >= maxCacheSize {
+		cache = make(map[string]*regexp.Regexp)
+	}
+
+	cache[pattern] = re
+
+	return re, nil
+}
+
+// regexArgument returns argument i, which can be a compiled regex or a string containing a pattern.
+func regexArgument(context *runtime.FunctionCallContext, i int) (*regexp.Regexp, error) {
+	arg := context.Args[i]
+
+	switch arg.Type {
+	case runtime.RegexValue:
+		return arg.Regex, nil
+	case runtime.StringValue:
+		re, err := compile(arg.Str)
+
+		if err != nil {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
+		}
+
+		return re, nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be of type regex or string, got %s", context.Name, i+1, arg.Type)
+	}
+}
+
+// countArgument returns the optional maximum amount of matches at argument i, or -1 for all matches.
+func countArgument(context *runtime.FunctionCallContext, i int) (int, error) {
+	if len(context.Args) <= i {
+		return -1, nil
+	}
+
+	if context.Args[i].Type != runtime.NumberValue {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be of type number, got %s", context.Name, i+1, context.Args[i].Type)
+	}
+
+	n, err := runtime.Int64Argument(context, i)
+
+	return int(n), err
+}
+
+func stringList(items []string) *runtime.Value {
+	var l []*runtime.Value
+
+	for _, item := range items {
+		l = append(l, runtime.NewStringValue(item))
+	}
+
+	return runtime.NewListValueOf(l)
+}
+
+// submatchList converts submatch indices to a list of strings, unmatched groups become nil.
+func submatchList(s string, indices []int) *runtime.Value {
+	var l []*runtime.Value
+
+	for i := 0; i < len(indices); i += 2 {
+		if indices[i] < 0 {
+			l = append(l, runtime.Nil)
+		} else {
+			l = append(l, runtime.NewStringValue(s[indices[i]:indices[i+1]]))
+		}
+	}
+
+	return runtime.NewListValueOf(l)
+}
+
+func regexCompile(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewRegexValue(re), nil
+}
+
+func regexQuote(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewStringValue(regexp.QuoteMeta(context.Args[0].Str)), nil
+}
+
+func regexMatch(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(re.MatchString(context.Args[1].Str)), nil
+}
+
+// regexFind returns the leftmost match, or nil if there is none.
+func regexFind(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	match := re.FindStringIndex(context.Args[1].Str)
+
+	if match == nil {
+		return runtime.Nil, nil
+	}
+
+	return runtime.NewStringValue(context.Args[1].Str[match[0]:match[1]]), nil
+}
+
+func regexFindAll(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stringList(re.FindAllString(context.Args[1].Str, n)), nil
+}
+
+// regexSubmatch returns a list of the leftmost match followed by its groups, or nil if there is no match.
+func regexSubmatch(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	indices := re.FindStringSubmatchIndex(context.Args[1].Str)
+
+	if indices == nil {
+		return runtime.Nil, nil
+	}
+
+	return submatchList(context.Args[1].Str, indices), nil
+}
+
+func regexSubmatchAll(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*runtime.Value
+
+	for _, indices := range re.FindAllStringSubmatchIndex(context.Args[1].Str, n) {
+		matches = append(matches, submatchList(context.Args[1].Str, indices))
+	}
+
+	return runtime.NewListValueOf(matches), nil
+}
+
+// regexNamed returns the named groups of the leftmost match as a keyword list, like (:year "2017" :month "03").
+// It returns nil if there is no match.
+func regexNamed(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	indices := re.FindStringSubmatchIndex(context.Args[1].Str)
+
+	if indices == nil {
+		return runtime.Nil, nil
+	}
+
+	groups := submatchList(context.Args[1].Str, indices)
+	var named []*runtime.Value
+
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			named = append(named, runtime.NewKeywordValue(name), groups.List.Get(i))
+		}
+	}
+
+	return runtime.NewListValueOf(named), nil
+}
+
+// regexReplace replaces all matches. The replacement is either a template in which $1 or ${name}
+// refer to groups, or a function that is called with the submatch list of every match and returns a string.
+func regexReplace(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := context.Args[1].Str
+	replacement := context.Args[2]
+
+	switch replacement.Type {
+	case runtime.StringValue:
+		return runtime.NewStringValue(re.ReplaceAllString(s, replacement.Str)), nil
+	case runtime.FunctionValue:
+		result := ""
+		last := 0
+
+		for _, indices := range re.FindAllStringSubmatchIndex(s, -1) {
+			value, err := replacement.Function.Call(context.Block, []*runtime.Value{submatchList(s, indices)}, context.Pos)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if value.Type != runtime.StringValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: expected the replacement function to return a string, got %s", context.Name, value.Type)
+			}
+
+			result += s[last:indices[0]] + value.Str
+			last = indices[1]
+		}
+
+		return runtime.NewStringValue(result + s[last:]), nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: argument 3 should be of type string or function, got %s", context.Name, replacement.Type)
+	}
+}
+
+func regexSplit(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+	}
+
+	re, err := regexArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := countArgument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stringList(re.Split(context.Args[1].Str, n)), nil
+}
@@ -1,12 +1,18 @@
 package runtime
 
+import "math/big"
+
 func ValidateArguments(context *FunctionCallContext, types ...ValueType) error {
 	if len(types) != len(context.Args) {
 		return NewRuntimeError(context.Pos, "%s: expected %d arguments, got %d", context.Name, len(types), len(context.Args))
 	}
 
 	for i, arg := range context.Args {
-		if types[i] != AnyValue {
+		if types[i] == SequenceValue {
+			if !IsSequence(arg) {
+				return NewRuntimeError(context.Pos, "%s: argument %d should be a list or a vector, got %s", context.Name, i+1, arg.Type)
+			}
+		} else if types[i] != AnyValue {
 			if arg.Type != types[i] {
 				return NewRuntimeError(context.Pos, "%s: argument %d should be of type %s, got %s", context.Name, i+1, types[i], arg.Type)
 			}
@@ -15,3 +21,45 @@ func ValidateArguments(context *FunctionCallContext, types ...ValueType) error {
 
 	return nil
 }
+
+// IntegerArgument returns argument i, which should be a number, as an integer.
+// An error is returned if the number isn't integral.
+func IntegerArgument(context *FunctionCallContext, i int) (*big.Int, error) {
+	arg := context.Args[i]
+
+	if !arg.NumberIsInteger() {
+		return nil, NewRuntimeError(context.Pos, "%s: argument %d should be an integer, got %s", context.Name, i+1, arg)
+	}
+
+	return arg.NumberToBigInt(), nil
+}
+
+// Int64Argument is like IntegerArgument, but also returns an error if the integer doesn't fit in an int64.
+// It is meant for indices, counts and sizes.
+func Int64Argument(context *FunctionCallContext, i int) (int64, error) {
+	n, err := IntegerArgument(context, i)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !n.IsInt64() {
+		return 0, NewRuntimeError(context.Pos, "%s: argument %d is out of range, got %s", context.Name, i+1, n)
+	}
+
+	return n.Int64(), nil
+}
+
+// BytesArgument returns argument i, which should be a string or bytes, as bytes. Strings are used as UTF-8.
+func BytesArgument(context *FunctionCallContext, i int) ([]byte, error) {
+	arg := context.Args[i]
+
+	switch arg.Type {
+	case StringValue:
+		return []byte(arg.Str), nil
+	case BytesValue:
+		return arg.Bytes, nil
+	default:
+		return nil, NewRuntimeError(context.Pos, "%s: argument %d should be a string or bytes, got %s", context.Name, i+1, arg.Type)
+	}
+}
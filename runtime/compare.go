@@ -0,0 +1,153 @@
+package runtime
+
+import (
+	"bytes"
+	"strings"
+	"unsafe"
+)
+
+// typeOrder is the order of values of different types in Compare.
+var typeOrder = map[ValueType]int{
+	NilValue:       0,
+	BooleanValue:   1,
+	NumberValue:    2,
+	CharValue:      3,
+	StringValue:    4,
+	KeywordValue:   5,
+	BytesValue:     6,
+	ListValue:      7,
+	VectorValue:    8,
+	SetValue:       9,
+	InstantValue:   10,
+	DurationValue:  11,
+	QuotedValue:    12,
+	RegexValue:     13,
+	ErrorValue:     14,
+	FunctionValue:  15,
+	PortValue:      16,
+	GeneratorValue: 17,
+	SeqValue:       18,
+}
+
+// Compare orders any two values, it returns -1, 0 or 1 and only returns 0 for values that are equal.
+// Values of different types are ordered by type: nil, booleans, numbers, chars, strings, keywords, bytes,
+// lists, vectors, sets, instants, durations, quoted values, regexes, errors, functions, ports, generators
+// and lazy sequences. Lists, vectors and sets (in their sorted order) are compared lexicographically.
+// Numbers are compared by value, an exact number comes before an inexact number that's equal to it and
+// NaN comes after all other numbers. Functions, ports, generators and lazy sequences are ordered by name
+// where they have one, and then by identity.
+func Compare(a *Value, b *Value) int {
+	if a.Type != b.Type {
+		return compareInts(typeOrder[a.Type], typeOrder[b.Type])
+	}
+
+	switch a.Type {
+	case BooleanValue:
+		return compareBools(a.Boolean, b.Boolean)
+	case NumberValue:
+		if a.NumberIsNaN() || b.NumberIsNaN() {
+			return compareBools(a.NumberIsNaN(), b.NumberIsNaN())
+		}
+
+		if cmp, _ := CompareNumbers(a, b); cmp != 0 {
+			return cmp
+		}
+
+		return compareBools(!a.NumberIsExact(), !b.NumberIsExact())
+	case CharValue:
+		return compareInts(int(a.Char), int(b.Char))
+	case StringValue:
+		return strings.Compare(a.Str, b.Str)
+	case KeywordValue:
+		return strings.Compare(a.Keyword, b.Keyword)
+	case BytesValue:
+		return bytes.Compare(a.Bytes, b.Bytes)
+	case ListValue:
+		return compareSlices(a.List.Values(), b.List.Values())
+	case VectorValue:
+		return compareSlices(a.Vector.Values(), b.Vector.Values())
+	case SetValue:
+		return compareSlices(a.Set.Values(), b.Set.Values())
+	case InstantValue:
+		return a.Instant.Compare(b.Instant)
+	case DurationValue:
+		return compareInts64(int64(a.Duration), int64(b.Duration))
+	case QuotedValue:
+		if cmp := strings.Compare(a.Quoted.Name(), b.Quoted.Name()); cmp != 0 {
+			return cmp
+		}
+
+		return strings.Compare(a.Quoted.String(), b.Quoted.String())
+	case RegexValue:
+		return strings.Compare(a.Regex.String(), b.Regex.String())
+	case ErrorValue:
+		if cmp := strings.Compare(a.Keyword, b.Keyword); cmp != 0 {
+			return cmp
+		}
+
+		return strings.Compare(a.Str, b.Str)
+	case FunctionValue:
+		if cmp := strings.Compare(a.Function.Name, b.Function.Name); cmp != 0 {
+			return cmp
+		}
+
+		return comparePointers(unsafe.Pointer(a.Function), unsafe.Pointer(b.Function))
+	case PortValue:
+		if cmp := strings.Compare(a.Port.Name, b.Port.Name); cmp != 0 {
+			return cmp
+		}
+
+		return comparePointers(unsafe.Pointer(a.Port), unsafe.Pointer(b.Port))
+	case GeneratorValue:
+		return comparePointers(unsafe.Pointer(a.Generator), unsafe.Pointer(b.Generator))
+	case SeqValue:
+		return comparePointers(unsafe.Pointer(a.Seq), unsafe.Pointer(b.Seq))
+	default:
+		return 0
+	}
+}
+
+func compareSlices(a []*Value, b []*Value) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if cmp := Compare(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+
+	return compareInts(len(a), len(b))
+}
+
+// compareBools orders false before true.
+func compareBools(a bool, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func compareInts64(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePointers orders values by identity. The order is arbitrary, but stable while the values exist.
+func comparePointers(a unsafe.Pointer, b unsafe.Pointer) int {
+	switch {
+	case uintptr(a) < uintptr(b):
+		return -1
+	case uintptr(a) > uintptr(b):
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1,6 +1,9 @@
 package runtime
 
-import "github.com/raoulvdberge/risp/parser"
+import (
+	"github.com/raoulvdberge/risp/parser"
+	"unicode/utf8"
+)
 
 func (b *Block) Eval() (*Value, error) {
 	var result *Value = Nil
@@ -27,13 +30,21 @@ func (b *Block) EvalNode(node parser.Node) (*Value, error) {
 	case *parser.StringNode:
 		return b.evalString(node), nil
 	case *parser.NumberNode:
-		return b.evalNumber(node), nil
+		return b.evalNumber(node)
 	case *parser.KeywordNode:
 		return b.evalKeyword(node), nil
+	case *parser.CharNode:
+		return b.evalChar(node), nil
+	case *parser.BytesNode:
+		return b.evalBytes(node), nil
 	case *parser.IdentifierNode:
 		return b.evalIdentifier(node)
 	case *parser.ListNode:
 		return b.evalList(node)
+	case *parser.SetNode:
+		return b.evalSet(node)
+	case *parser.VectorNode:
+		return b.evalVector(node)
 	case *parser.QuoteNode:
 		return b.evalQuote(node)
 	default:
@@ -45,14 +56,30 @@ func (b *Block) evalString(node *parser.StringNode) *Value {
 	return NewStringValue(node.Token.Data)
 }
 
-func (b *Block) evalNumber(node *parser.NumberNode) *Value {
-	return NewNumberValueFromString(node.Token.Data)
+func (b *Block) evalNumber(node *parser.NumberNode) (*Value, error) {
+	value, err := NewNumberValueFromString(node.Token.Data)
+
+	if err != nil {
+		return nil, NewRuntimeError(node.Pos(), "%s", err.Error())
+	}
+
+	return value, nil
 }
 
 func (b *Block) evalKeyword(node *parser.KeywordNode) *Value {
 	return NewKeywordValue(node.Token.Data)
 }
 
+func (b *Block) evalChar(node *parser.CharNode) *Value {
+	r, _ := utf8.DecodeRuneInString(node.Token.Data)
+
+	return NewCharValue(r)
+}
+
+func (b *Block) evalBytes(node *parser.BytesNode) *Value {
+	return NewBytesValue([]byte(node.Token.Data))
+}
+
 func (b *Block) evalIdentifier(node *parser.IdentifierNode) (*Value, error) {
 	name := node.Token.Data
 	ref := false
@@ -105,6 +132,40 @@ func (b *Block) evalList(node *parser.ListNode) (*Value, error) {
 	return b.evalSingleList(node)
 }
 
+// evalSet evaluates the values of a set literal.
+func (b *Block) evalSet(node *parser.SetNode) (*Value, error) {
+	set := NewSet()
+
+	for _, item := range node.Nodes {
+		value, err := b.EvalNode(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		set.Insert(value)
+	}
+
+	return NewSetValue(set), nil
+}
+
+// evalVector evaluates the items of a vector literal.
+func (b *Block) evalVector(node *parser.VectorNode) (*Value, error) {
+	var items []*Value
+
+	for _, item := range node.Nodes {
+		value, err := b.EvalNode(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, value)
+	}
+
+	return NewVectorValue(NewVector(items)), nil
+}
+
 func (b *Block) evalSingleList(node *parser.ListNode) (*Value, error) {
 	if len(node.Nodes) < 1 {
 		return nil, NewRuntimeError(node.Pos(), "expected a function or macro name")
@@ -122,27 +183,21 @@ func (b *Block) evalSingleList(node *parser.ListNode) (*Value, error) {
 		macro := b.Scope.GetMacro(name)
 		args := node.Nodes[1:] // omit the macro name
 
-		if macro.typeChecking {
-			if len(macro.Types) != len(args) {
-				return nil, NewRuntimeError(node.Pos(), "macro '%s' expected %d arguments, got %d", name, len(macro.Types), len(args))
-			}
-
-			for i, macroArg := range macro.Types {
-				if macroArg != "any" {
-					if macroArg != args[i].Name() {
-						return nil, NewRuntimeError(node.Pos(), "macro '%s' expected that argument %d should be of type %s, not %s", name, i+1, macroArg, args[i].Name())
-					}
-				}
-			}
-		}
-
-		return macro.Handler(&MacroCallContext{
+		context := &MacroCallContext{
 			Macro: macro,
 			Block: b,
 			Nodes: args,
 			Pos:   nameNode.Pos(),
 			Name:  name,
-		})
+		}
+
+		if macro.typeChecking {
+			if err := ValidateMacroArguments(context); err != nil {
+				return nil, err
+			}
+		}
+
+		return macro.Handler(context)
 	} else if b.Scope.HasSymbol(name) {
 		value := b.Scope.GetSymbol(name).Value
 
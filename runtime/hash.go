@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"unsafe"
+)
+
+// Hash returns a hash of the value that's consistent with Equals: values that are equal have the same hash.
+// Functions, ports, generators and lazy sequences are hashed by identity.
+func (v *Value) Hash() uint64 {
+	h := fnv.New64a()
+
+	v.writeHash(h)
+
+	return h.Sum64()
+}
+
+func (v *Value) writeHash(h hash.Hash64) {
+	var buffer [8]byte
+
+	writeUint64 := func(n uint64) {
+		binary.LittleEndian.PutUint64(buffer[:], n)
+		h.Write(buffer[:])
+	}
+
+	writeString := func(s string) {
+		writeUint64(uint64(len(s)))
+		h.Write([]byte(s))
+	}
+
+	h.Write([]byte{byte(v.Type)})
+
+	switch v.Type {
+	case StringValue:
+		writeString(v.Str)
+	case NumberValue:
+		// exact and inexact numbers are never equal, but inexact numbers of different precisions can be
+		switch {
+		case v.NumberIsExact():
+			writeString(v.Number.String())
+		case v.NumberIsNaN():
+			writeUint64(math.Float64bits(math.NaN()))
+		default:
+			f := v.NumberToFloat64()
+
+			if f == 0 {
+				f = 0 // -0 is equal to 0
+			}
+
+			writeUint64(math.Float64bits(f))
+		}
+	case BooleanValue:
+		if v.Boolean {
+			writeUint64(1)
+		} else {
+			writeUint64(0)
+		}
+	case KeywordValue:
+		writeString(v.Keyword)
+	case ListValue:
+		writeUint64(uint64(v.List.Len()))
+
+		for _, item := range v.List.Values() {
+			item.writeHash(h)
+		}
+	case VectorValue:
+		writeUint64(uint64(v.Vector.Len()))
+
+		for _, item := range v.Vector.Values() {
+			item.writeHash(h)
+		}
+	case QuotedValue:
+		writeString(v.Quoted.Name())
+		writeString(v.Quoted.String())
+	case CharValue:
+		writeUint64(uint64(v.Char))
+	case BytesValue:
+		writeString(string(v.Bytes))
+	case RegexValue:
+		writeString(v.Regex.String())
+	case ErrorValue:
+		writeString(v.Keyword)
+		writeString(v.Str)
+	case InstantValue:
+		// instants in different zones can be equal
+		writeUint64(uint64(v.Instant.Unix()))
+		writeUint64(uint64(v.Instant.Nanosecond()))
+	case DurationValue:
+		writeUint64(uint64(v.Duration))
+	case FunctionValue:
+		writeUint64(uint64(uintptr(unsafe.Pointer(v.Function))))
+	case PortValue:
+		writeUint64(uint64(uintptr(unsafe.Pointer(v.Port))))
+	case GeneratorValue:
+		writeUint64(uint64(uintptr(unsafe.Pointer(v.Generator))))
+	case SeqValue:
+		writeUint64(uint64(uintptr(unsafe.Pointer(v.Seq))))
+	case SetValue:
+		// the hash of a set doesn't depend on the order of its values
+		sum := uint64(0)
+
+		v.Set.Each(func(item *Value) {
+			sum += item.Hash()
+		})
+
+		writeUint64(uint64(v.Set.size))
+		writeUint64(sum)
+	}
+}
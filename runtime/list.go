@@ -0,0 +1,178 @@
+package runtime
+
+// List is a linked list of cons cells followed by a persistent vector of the items that were pushed at
+// the end. A nil *List is the empty list. Lists are never changed once they're built, so lists can share
+// cells and vectors: cons and cdr take constant time because they only add or skip a cell, and push and
+// drop-last are amortized constant time because they only change the vector.
+type List struct {
+	front *cell
+	back  *Vector // nil if nothing was pushed
+}
+
+type cell struct {
+	head *Value
+	tail *cell
+	size int // the number of cells from this one onwards
+}
+
+// NewList returns a list of the given items.
+func NewList(items []*Value) *List {
+	var front *cell
+
+	for i := len(items) - 1; i >= 0; i-- {
+		front = &cell{head: items[i], tail: front, size: len(items) - i}
+	}
+
+	if front == nil {
+		return nil
+	}
+
+	return &List{front: front}
+}
+
+func (l *List) frontLen() int {
+	if l == nil || l.front == nil {
+		return 0
+	}
+
+	return l.front.size
+}
+
+func (l *List) backLen() int {
+	if l == nil || l.back == nil {
+		return 0
+	}
+
+	return l.back.Len()
+}
+
+// Cons returns a list of an item followed by the items of l.
+func (l *List) Cons(item *Value) *List {
+	if l == nil {
+		return &List{front: &cell{head: item, size: 1}}
+	}
+
+	return &List{front: &cell{head: item, tail: l.front, size: l.frontLen() + 1}, back: l.back}
+}
+
+func (l *List) Len() int {
+	return l.frontLen() + l.backLen()
+}
+
+// First returns the first item of a non-empty list.
+func (l *List) First() *Value {
+	if l.front != nil {
+		return l.front.head
+	}
+
+	return l.back.Get(0)
+}
+
+// Rest returns a list without its first item, the rest of the empty list is the empty list.
+func (l *List) Rest() *List {
+	if l.Len() <= 1 {
+		return nil
+	}
+
+	if l.front != nil {
+		return &List{front: l.front.tail, back: l.back}
+	}
+
+	return &List{back: l.back.Drop(1)}
+}
+
+// Get returns the item at index i, which should be in bounds. It takes O(i) for items in the cells.
+func (l *List) Get(i int) *Value {
+	if i >= l.frontLen() {
+		return l.back.Get(i - l.frontLen())
+	}
+
+	c := l.front
+
+	for ; i > 0; i-- {
+		c = c.tail
+	}
+
+	return c.head
+}
+
+// Values returns the items of the list in a new slice.
+func (l *List) Values() []*Value {
+	values := make([]*Value, 0, l.Len())
+
+	if l == nil {
+		return values
+	}
+
+	for c := l.front; c != nil; c = c.tail {
+		values = append(values, c.head)
+	}
+
+	if l.back != nil {
+		values = append(values, l.back.Values()...)
+	}
+
+	return values
+}
+
+// Set returns a list with the item at index i, which should be in bounds, replaced.
+// Only the cells in front of index i are copied.
+func (l *List) Set(i int, item *Value) *List {
+	if i >= l.frontLen() {
+		return &List{front: l.front, back: l.back.Set(i-l.frontLen(), item)}
+	}
+
+	return &List{front: setCell(l.front, i, item), back: l.back}
+}
+
+func setCell(c *cell, i int, item *Value) *cell {
+	if i == 0 {
+		return &cell{head: item, tail: c.tail, size: c.size}
+	}
+
+	return &cell{head: c.head, tail: setCell(c.tail, i-1, item), size: c.size}
+}
+
+// Push returns a list with an item added at the end.
+func (l *List) Push(item *Value) *List {
+	if l == nil {
+		return &List{back: EmptyVector().Push(item)}
+	}
+
+	back := l.back
+
+	if back == nil {
+		back = EmptyVector()
+	}
+
+	return &List{front: l.front, back: back.Push(item)}
+}
+
+// Pop returns a list without its last item, the list shouldn't be empty. If the last item is in the
+// cells, the cells are moved into the vector once, so popping the result again is cheap.
+func (l *List) Pop() *List {
+	if l.Len() == 1 {
+		return nil
+	}
+
+	if l.backLen() > 0 {
+		return &List{front: l.front, back: l.back.Pop()}
+	}
+
+	values := l.Values()
+
+	return &List{back: NewVector(values[:len(values)-1])}
+}
+
+// Append returns a list with the items of other added at the end.
+func (l *List) Append(other *List) *List {
+	if l == nil {
+		return other
+	}
+
+	for _, item := range other.Values() {
+		l = l.Push(item)
+	}
+
+	return l
+}
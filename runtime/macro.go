@@ -28,3 +28,23 @@ type MacroCallContext struct {
 	Pos   *lexer.TokenPos
 	Name  string
 }
+
+// ValidateMacroArguments checks the nodes passed to a macro against its types.
+// Macros without type checking can call it themselves after handling other forms.
+func ValidateMacroArguments(context *MacroCallContext) error {
+	macro := context.Macro
+
+	if len(macro.Types) != len(context.Nodes) {
+		return NewRuntimeError(context.Pos, "macro '%s' expected %d arguments, got %d", context.Name, len(macro.Types), len(context.Nodes))
+	}
+
+	for i, macroArg := range macro.Types {
+		if macroArg != "any" {
+			if macroArg != context.Nodes[i].Name() {
+				return NewRuntimeError(context.Pos, "macro '%s' expected that argument %d should be of type %s, not %s", context.Name, i+1, macroArg, context.Nodes[i].Name())
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,533 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"github.com/raoulvdberge/risp/util"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseNumber converts a number literal as accepted by the lexer to a rational number.
+// Literals can have a sign, a radix prefix (0x, 0o or 0b), digit separators (_),
+// a fractional part, an exponent, or be written as a fraction (1/3).
+func ParseNumber(value string) (*big.Rat, error) {
+	value = strings.Replace(value, "_", "", -1)
+
+	if i := strings.Index(value, "/"); i != -1 {
+		numerator, err := parseInteger(value[:i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		denominator, err := parseInteger(value[i+1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if denominator.Sign() == 0 {
+			return nil, fmt.Errorf("zero denominator in rational literal '%s'", value)
+		}
+
+		return new(big.Rat).SetFrac(numerator, denominator), nil
+	}
+
+	if base, _ := util.RadixPrefix(strings.TrimLeft(value, "+-")); base != 10 {
+		integer, err := parseInteger(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return new(big.Rat).SetInt(integer), nil
+	}
+
+	number, ok := new(big.Rat).SetString(value)
+
+	if !ok {
+		return nil, fmt.Errorf("malformed number '%s'", value)
+	}
+
+	return number, nil
+}
+
+func parseInteger(value string) (*big.Int, error) {
+	digits := strings.TrimLeft(value, "+-")
+
+	if len(value)-len(digits) > 1 {
+		return nil, fmt.Errorf("malformed integer '%s'", value)
+	}
+
+	base, _ := util.RadixPrefix(digits)
+
+	if base != 10 {
+		digits = digits[2:]
+	}
+
+	integer, ok := new(big.Int).SetString(digits, base)
+
+	if !ok {
+		return nil, fmt.Errorf("malformed integer '%s'", value)
+	}
+
+	if strings.HasPrefix(value, "-") {
+		integer.Neg(integer)
+	}
+
+	return integer, nil
+}
+
+// CompareNumbers compares two numbers, exactly. It returns false if the numbers
+// are unordered, which is the case if one of them is NaN.
+func CompareNumbers(a *Value, b *Value) (int, bool) {
+	if a.NumberIsExact() && b.NumberIsExact() {
+		return a.Number.Cmp(b.Number), true
+	}
+
+	if a.NumberIsNaN() || b.NumberIsNaN() {
+		return 0, false
+	}
+
+	if !a.NumberIsFinite() || !b.NumberIsFinite() {
+		return compareInts(infinitySign(a), infinitySign(b)), true
+	}
+
+	x, _ := a.NumberToRat()
+	y, _ := b.NumberToRat()
+
+	return x.Cmp(y), true
+}
+
+// NumberArithmetic applies +, -, * or / to two numbers. The result is exact if both numbers are exact,
+// an arbitrary precision float if one of them is (using the highest precision of the two), and a float64 otherwise.
+func NumberArithmetic(op string, a *Value, b *Value) (*Value, error) {
+	kind := a.NumberKind
+
+	if b.NumberKind > kind {
+		kind = b.NumberKind
+	}
+
+	switch kind {
+	case ExactNumber:
+		result := new(big.Rat)
+
+		switch op {
+		case "+":
+			result.Add(a.Number, b.Number)
+		case "-":
+			result.Sub(a.Number, b.Number)
+		case "*":
+			result.Mul(a.Number, b.Number)
+		case "/":
+			if b.Number.Sign() == 0 {
+				return nil, errors.New("division by zero")
+			}
+
+			result.Quo(a.Number, b.Number)
+		}
+
+		return NewNumberValueFromRat(result), nil
+	case FloatNumber:
+		x, y := a.NumberToFloat64(), b.NumberToFloat64()
+
+		switch op {
+		case "+":
+			return NewNumberValueFromFloat64(x + y), nil
+		case "-":
+			return NewNumberValueFromFloat64(x - y), nil
+		case "*":
+			return NewNumberValueFromFloat64(x * y), nil
+		default:
+			return NewNumberValueFromFloat64(x / y), nil
+		}
+	default:
+		prec := a.numberPrecision()
+
+		if b.numberPrecision() > prec {
+			prec = b.numberPrecision()
+		}
+
+		if a.NumberIsNaN() || b.NumberIsNaN() {
+			return nil, errors.New("NaN can't be used in arbitrary precision arithmetic")
+		}
+
+		x, y := a.numberToBigFloat(prec), b.numberToBigFloat(prec)
+		result := new(big.Float).SetPrec(prec)
+
+		err := BigFloatOperation(func() {
+			switch op {
+			case "+":
+				result.Add(x, y)
+			case "-":
+				result.Sub(x, y)
+			case "*":
+				result.Mul(x, y)
+			case "/":
+				result.Quo(x, y)
+			}
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewNumberValueFromBigFloat(result), nil
+	}
+}
+
+// BigFloatOperation runs an operation on big.Float values, turning the panic
+// that is raised when the result would be NaN into an error.
+func BigFloatOperation(operation func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			nan, ok := r.(big.ErrNaN)
+
+			if !ok {
+				panic(r)
+			}
+
+			err = errors.New(nan.Error())
+		}
+	}()
+
+	operation()
+
+	return nil
+}
+
+// numberToBigFloat converts the number to a big.Float with the given precision.
+// A precision of 0 keeps the precision of the number. NaN is not supported.
+func (v *Value) numberToBigFloat(prec uint) *big.Float {
+	if prec == 0 {
+		prec = v.numberPrecision()
+	}
+
+	f := new(big.Float).SetPrec(prec)
+
+	switch v.NumberKind {
+	case FloatNumber:
+		return f.SetFloat64(v.Float)
+	case BigFloatNumber:
+		return f.Set(v.BigFloat)
+	default:
+		return f.SetRat(v.Number)
+	}
+}
+
+// numberPrecision returns the precision in bits of an inexact number, and of a float64 for exact numbers.
+func (v *Value) numberPrecision() uint {
+	if v.NumberKind == BigFloatNumber {
+		return v.BigFloat.Prec()
+	}
+
+	return 53
+}
+
+func (v *Value) numberString() string {
+	switch v.NumberKind {
+	case FloatNumber:
+		if math.IsNaN(v.Float) {
+			return "+nan.0"
+		}
+
+		if math.IsInf(v.Float, 0) {
+			return infinityString(v.Float > 0)
+		}
+
+		return inexactString(strconv.FormatFloat(v.Float, 'g', -1, 64))
+	case BigFloatNumber:
+		if v.BigFloat.IsInf() {
+			return infinityString(v.BigFloat.Sign() > 0)
+		}
+
+		return inexactString(v.BigFloat.Text('g', -1))
+	default:
+		if v.Number.IsInt() {
+			return v.Number.Num().String()
+		}
+
+		if digits, ok := decimalDigits(v.Number.Denom()); ok {
+			return v.Number.FloatString(digits)
+		}
+
+		return v.Number.String()
+	}
+}
+
+// decimalDigits returns the amount of digits after the decimal point needed to write 1/denominator.
+// It returns false if 1/denominator has no finite decimal expansion.
+func decimalDigits(denominator *big.Int) (int, bool) {
+	d := new(big.Int).Set(denominator)
+	m := new(big.Int)
+
+	twos, fives := 0, 0
+
+	for d.Bit(0) == 0 {
+		d.Rsh(d, 1)
+		twos++
+	}
+
+	for {
+		q, _ := new(big.Int).QuoRem(d, big.NewInt(5), m)
+
+		if m.Sign() != 0 {
+			break
+		}
+
+		d = q
+		fives++
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+
+	if twos > fives {
+		return twos, true
+	}
+
+	return fives, true
+}
+
+// inexactString makes sure an inexact number is never printed as an integer, so it doesn't read back as an exact number.
+func inexactString(s string) string {
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
+
+func infinityString(positive bool) string {
+	if positive {
+		return "+inf.0"
+	}
+
+	return "-inf.0"
+}
+
+func infinitySign(v *Value) int {
+	switch {
+	case v.NumberIsFinite():
+		return 0
+	case v.NumberKind == FloatNumber:
+		if v.Float > 0 {
+			return 1
+		}
+
+		return -1
+	default:
+		return v.BigFloat.Sign()
+	}
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NumberFormat describes how FormatNumber writes a number.
+type NumberFormat struct {
+	Radix     int    // 2 to 36, only exact numbers can be written in a radix other than 10
+	Notation  string // "fixed", "scientific", "rational" or "" for the notation used by Value.String
+	Precision int    // digits after the decimal point for fixed and scientific notation, -1 for the shortest exact representation
+	Separator string // inserted between groups of thousands in the integer part, if not empty
+}
+
+func NewNumberFormat() *NumberFormat {
+	return &NumberFormat{Radix: 10, Precision: -1}
+}
+
+// FormatNumber writes a number value according to the given format.
+func FormatNumber(v *Value, format *NumberFormat) (string, error) {
+	s, err := formatNumber(v, format)
+
+	if err != nil {
+		return "", err
+	}
+
+	if format.Separator != "" {
+		s = groupThousands(s, format.Separator)
+	}
+
+	return s, nil
+}
+
+func formatNumber(v *Value, format *NumberFormat) (string, error) {
+	if format.Radix != 10 && format.Notation != "" && format.Notation != "rational" {
+		return "", fmt.Errorf("%s notation is only supported in radix 10", format.Notation)
+	}
+
+	if !v.NumberIsFinite() || v.NumberIsNaN() {
+		return v.String(), nil
+	}
+
+	switch format.Notation {
+	case "rational":
+		r, _ := v.NumberToRat()
+
+		return formatRational(r, format.Radix), nil
+	case "fixed":
+		if format.Precision < 0 {
+			if v.NumberIsExact() {
+				if digits, ok := decimalDigits(v.Number.Denom()); ok {
+					return v.Number.FloatString(digits), nil
+				}
+
+				return "", fmt.Errorf("%s has no finite decimal representation, a precision is required", v)
+			}
+
+			return v.numberToBigFloat(0).Text('f', -1), nil
+		}
+
+		if v.NumberIsExact() {
+			return v.Number.FloatString(format.Precision), nil
+		}
+
+		return v.numberToBigFloat(0).Text('f', format.Precision), nil
+	case "scientific":
+		f := v.numberToBigFloat(0)
+
+		if v.NumberIsExact() {
+			// enough bits to represent the requested amount of decimal digits, or a float64 for the shortest representation
+			prec := uint(53)
+
+			if format.Precision >= 0 {
+				prec = uint(format.Precision)*4 + 64
+			}
+
+			f = new(big.Float).SetPrec(prec).SetRat(v.Number)
+		}
+
+		return f.Text('e', format.Precision), nil
+	case "":
+		if format.Radix == 10 {
+			return v.String(), nil
+		}
+
+		if !v.NumberIsExact() {
+			return "", fmt.Errorf("only exact numbers can be written in radix %d", format.Radix)
+		}
+
+		return formatRational(v.Number, format.Radix), nil
+	default:
+		return "", fmt.Errorf("unknown notation '%s'", format.Notation)
+	}
+}
+
+func formatRational(r *big.Rat, radix int) string {
+	if r.IsInt() {
+		return r.Num().Text(radix)
+	}
+
+	return r.Num().Text(radix) + "/" + r.Denom().Text(radix)
+}
+
+// groupThousands inserts a separator between groups of three digits in the first run of digits of s.
+func groupThousands(s string, separator string) string {
+	start := strings.IndexFunc(s, isDigitRune)
+
+	if start == -1 {
+		return s
+	}
+
+	end := start
+
+	for end < len(s) && isDigitRune(rune(s[end])) {
+		end++
+	}
+
+	digits := s[start:end]
+	grouped := ""
+
+	for i, digit := range digits {
+		if i != 0 && (len(digits)-i)%3 == 0 {
+			grouped += separator
+		}
+
+		grouped += string(digit)
+	}
+
+	return s[:start] + grouped + s[end:]
+}
+
+func isDigitRune(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z')
+}
+
+// ParseNumberInRadix parses an integer or a fraction of integers (a/b) written in the given radix.
+func ParseNumberInRadix(value string, radix int) (*big.Rat, error) {
+	parts := strings.Split(value, "/")
+
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("more than one '/' in '%s'", value)
+	}
+
+	var integers []*big.Int
+
+	offset := 0
+
+	for _, part := range parts {
+		digits := part
+
+		if len(integers) == 0 && (strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-")) {
+			digits = digits[1:]
+		}
+
+		if digits == "" {
+			return nil, fmt.Errorf("expected digits at position %d", offset+len(part)-len(digits)+1)
+		}
+
+		for i, r := range digits {
+			if r > unicode.MaxASCII || !isRadixDigit(byte(r), radix) {
+				return nil, fmt.Errorf("invalid digit '%c' for radix %d at position %d", r, radix, offset+len(part)-len(digits)+i+1)
+			}
+		}
+
+		integer, _ := new(big.Int).SetString(digits, radix)
+
+		if strings.HasPrefix(part, "-") {
+			integer.Neg(integer)
+		}
+
+		integers = append(integers, integer)
+
+		offset += len(part) + 1
+	}
+
+	if len(integers) == 1 {
+		return new(big.Rat).SetInt(integers[0]), nil
+	}
+
+	if integers[1].Sign() == 0 {
+		return nil, fmt.Errorf("zero denominator in '%s'", value)
+	}
+
+	return new(big.Rat).SetFrac(integers[0], integers[1]), nil
+}
+
+func isRadixDigit(c byte, radix int) bool {
+	value := radix
+
+	switch {
+	case c >= '0' && c <= '9':
+		value = int(c - '0')
+	case c >= 'a' && c <= 'z':
+		value = int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		value = int(c-'A') + 10
+	}
+
+	return value < radix
+}
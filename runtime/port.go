@@ -0,0 +1,216 @@
+package runtime
+
+import (
+	"bufio"
+	"errors"
+	"github.com/raoulvdberge/risp/parser"
+	"io"
+	"os"
+	"strings"
+)
+
+// Port is a stream that risp code reads from or writes to, like the standard streams or an opened file.
+type Port struct {
+	Name   string
+	reader *bufio.Reader
+	writer io.Writer
+	closer io.Closer
+	closed bool
+	forms  []parser.Node // forms read by ReadForm that weren't returned yet
+}
+
+var (
+	Stdin  = NewInputPort("stdin", os.Stdin)
+	Stdout = NewOutputPort("stdout", os.Stdout)
+	Stderr = NewOutputPort("stderr", os.Stderr)
+)
+
+// output is the port print and println write to.
+var output = Stdout
+
+// buffered are the buffered ports that aren't closed yet, FlushPorts writes their buffers before risp exits.
+var buffered = map[*Port]bool{}
+
+// NewInputPort returns a port reading from r. If r is an io.Closer, closing the port closes r.
+func NewInputPort(name string, r io.Reader) *Port {
+	port := &Port{Name: name, reader: bufio.NewReader(r)}
+
+	if closer, ok := r.(io.Closer); ok {
+		port.closer = closer
+	}
+
+	return port
+}
+
+// NewOutputPort returns a port writing to w without buffering. If w is an io.Closer, closing the port closes w.
+func NewOutputPort(name string, w io.Writer) *Port {
+	port := &Port{Name: name, writer: w}
+
+	if closer, ok := w.(io.Closer); ok {
+		port.closer = closer
+	}
+
+	return port
+}
+
+// NewBufferedOutputPort returns a port writing to w through a buffer, which is written by Flush and Close,
+// or by FlushPorts if the port is still open when risp exits. Closing the port closes w.
+func NewBufferedOutputPort(name string, w io.WriteCloser) *Port {
+	port := &Port{Name: name, writer: bufio.NewWriter(w), closer: w}
+
+	buffered[port] = true
+
+	return port
+}
+
+// FlushPorts writes the buffers of the buffered ports that are still open. It should be called on every
+// path that ends risp, so data written to a file that isn't closed isn't lost. It returns the first error.
+func FlushPorts() error {
+	var err error
+
+	for port := range buffered {
+		if flushErr := port.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+
+	return err
+}
+
+// CurrentOutput returns the port print and println write to, which is stdout unless
+// it's redirected by WithOutputToString.
+func CurrentOutput() *Port {
+	return output
+}
+
+// WithOutputToString calls fn with the current output redirected to a string, which is returned.
+func WithOutputToString(fn func() error) (string, error) {
+	var buffer strings.Builder
+
+	previous := output
+	output = NewOutputPort("string", &buffer)
+
+	defer func() {
+		output = previous
+	}()
+
+	err := fn()
+
+	return buffer.String(), err
+}
+
+func (p *Port) IsInput() bool {
+	return p.reader != nil
+}
+
+func (p *Port) IsOutput() bool {
+	return p.writer != nil
+}
+
+// Reader returns the reader of an input port, for functions that decode the port themselves.
+func (p *Port) Reader() io.Reader {
+	return p.reader
+}
+
+// ReadLine reads the next line without its line ending. It returns false at the end of the stream.
+func (p *Port) ReadLine() (string, bool, error) {
+	if p.closed {
+		return "", false, errors.New("port is closed")
+	}
+
+	line, err := p.reader.ReadString('\n')
+
+	if err == io.EOF {
+		return line, line != "", nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true, nil
+}
+
+// ReadChar reads the next character. It returns false at the end of the stream.
+func (p *Port) ReadChar() (rune, bool, error) {
+	if p.closed {
+		return 0, false, errors.New("port is closed")
+	}
+
+	r, _, err := p.reader.ReadRune()
+
+	if err == io.EOF {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return r, true, nil
+}
+
+// ReadAll reads until the end of the stream.
+func (p *Port) ReadAll() (string, error) {
+	if p.closed {
+		return "", errors.New("port is closed")
+	}
+
+	data, err := io.ReadAll(p.reader)
+
+	return string(data), err
+}
+
+func (p *Port) Write(s string) error {
+	if p.closed {
+		return errors.New("port is closed")
+	}
+
+	_, err := io.WriteString(p.writer, s)
+
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (p *Port) Flush() error {
+	if flusher, ok := p.writer.(interface{ Flush() error }); ok && !p.closed {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+func (p *Port) Close() error {
+	if p.closed {
+		return nil
+	}
+
+	err := p.Flush()
+
+	p.closed = true
+	delete(buffered, p)
+
+	if p.closer != nil {
+		if closeErr := p.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// NewIOErrorValue converts an I/O error into an error value, with a kind describing the failure.
+func NewIOErrorValue(err error) *Value {
+	kind := "io"
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		kind = "not-found"
+	case errors.Is(err, os.ErrExist):
+		kind = "exists"
+	case errors.Is(err, os.ErrPermission):
+		kind = "permission"
+	}
+
+	return NewErrorValue(kind, err.Error())
+}
@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"github.com/raoulvdberge/risp/lexer"
+	"github.com/raoulvdberge/risp/parser"
+	"unicode/utf8"
+)
+
+// ParseForms lexes and parses all forms in data, without evaluating them.
+func ParseForms(name string, data string) ([]parser.Node, error) {
+	l := lexer.NewLexer(lexer.NewSourceFromString(name, data))
+
+	if err := l.Lex(); err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(l.Tokens)
+
+	if err := p.Parse(); err != nil {
+		return nil, err
+	}
+
+	return p.Nodes, nil
+}
+
+// ReadForm reads the next form from an input port. It reads whole lines until they contain
+// a complete form, forms that follow it on those lines are returned by the next calls.
+// It returns false at the end of the port.
+func (p *Port) ReadForm() (parser.Node, bool, error) {
+	text := ""
+
+	for len(p.forms) == 0 {
+		line, ok, err := p.ReadLine()
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !ok {
+			if text == "" {
+				return nil, false, nil
+			}
+
+			// the input ends in an incomplete form
+			_, err := ParseForms(p.Name, text)
+
+			return nil, false, err
+		}
+
+		text += line + "\n"
+
+		forms, err := ParseForms(p.Name, text)
+
+		var syntaxErr *lexer.SyntaxError
+
+		if errors.As(err, &syntaxErr) && syntaxErr.Incomplete() {
+			continue
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		p.forms = forms
+		text = ""
+	}
+
+	form := p.forms[0]
+	p.forms = p.forms[1:]
+
+	return form, true, nil
+}
+
+// NodeToData converts a parsed form into a value without evaluating it. Lists become list values,
+// t, f and nil become their values and other identifiers and quoted forms become quoted values.
+// Forms that can't be converted, like malformed number literals, return a *lexer.SyntaxError.
+func NodeToData(node parser.Node) (*Value, error) {
+	switch node := node.(type) {
+	case *parser.StringNode:
+		return NewStringValue(node.Token.Data), nil
+	case *parser.NumberNode:
+		value, err := NewNumberValueFromString(node.Token.Data)
+
+		if err != nil {
+			return nil, lexer.NewSyntaxError(node.Token.Pos, "%s", err.Error())
+		}
+
+		return value, nil
+	case *parser.KeywordNode:
+		return NewKeywordValue(node.Token.Data), nil
+	case *parser.CharNode:
+		r, _ := utf8.DecodeRuneInString(node.Token.Data)
+
+		return NewCharValue(r), nil
+	case *parser.BytesNode:
+		return NewBytesValue([]byte(node.Token.Data)), nil
+	case *parser.IdentifierNode:
+		switch node.Token.Data {
+		case "t":
+			return True, nil
+		case "f":
+			return False, nil
+		case "nil":
+			return Nil, nil
+		default:
+			return NewQuotedValue(node), nil
+		}
+	case *parser.ListNode:
+		var items []*Value
+
+		for _, item := range node.Nodes {
+			value, err := NodeToData(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, value)
+		}
+
+		return NewListValueOf(items), nil
+	case *parser.SetNode:
+		set := NewSet()
+
+		for _, item := range node.Nodes {
+			value, err := NodeToData(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			set.Insert(value)
+		}
+
+		return NewSetValue(set), nil
+	case *parser.VectorNode:
+		var items []*Value
+
+		for _, item := range node.Nodes {
+			value, err := NodeToData(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, value)
+		}
+
+		return NewVectorValue(NewVector(items)), nil
+	case *parser.QuoteNode:
+		return NewQuotedValue(node.Node), nil
+	default:
+		return nil, lexer.NewSyntaxError(node.Pos(), "unexpected %s", node.Name())
+	}
+}
+
+// NewSyntaxErrorValue converts a syntax error into an error value of kind :syntax.
+func NewSyntaxErrorValue(err *lexer.SyntaxError) *Value {
+	pos := err.Pos()
+
+	return NewErrorValue("syntax", fmt.Sprintf("%s(%d:%d): %s", pos.Source.Name(), pos.Line, pos.Col, err.Message()))
+}
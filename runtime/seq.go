@@ -0,0 +1,125 @@
+package runtime
+
+// Seq is a lazy sequence. Its items are computed one at a time when they're first needed and then cached,
+// so a sequence can be realized more than once without computing its items again. Only the part of a
+// sequence that's still referenced is kept in memory, so an infinite sequence can be walked in constant memory.
+type Seq struct {
+	// next computes the first item and the rest of the sequence, it returns a nil rest at the end.
+	// It's nil once the sequence is realized.
+	next  func() (*Value, *Seq, error)
+	first *Value
+	rest  *Seq
+}
+
+func NewSeq(next func() (*Value, *Seq, error)) *Seq {
+	return &Seq{next: next}
+}
+
+// EmptySeq returns a realized sequence without items.
+func EmptySeq() *Seq {
+	return &Seq{}
+}
+
+// Cons returns a realized sequence of an item followed by another sequence.
+func Cons(first *Value, rest *Seq) *Seq {
+	return &Seq{first: first, rest: rest}
+}
+
+// NewListSeq returns a sequence of the items of a list.
+func NewListSeq(items []*Value) *Seq {
+	if len(items) == 0 {
+		return EmptySeq()
+	}
+
+	return NewSeq(func() (*Value, *Seq, error) {
+		return items[0], NewListSeq(items[1:]), nil
+	})
+}
+
+// realize computes the first item if it isn't yet. If that fails, it's tried again the next time.
+func (s *Seq) realize() error {
+	if s.next == nil {
+		return nil
+	}
+
+	first, rest, err := s.next()
+
+	if err != nil {
+		return err
+	}
+
+	s.next, s.first, s.rest = nil, first, rest
+
+	return nil
+}
+
+// First returns the first item, it returns false if the sequence is empty.
+func (s *Seq) First() (*Value, bool, error) {
+	if err := s.realize(); err != nil {
+		return nil, false, err
+	}
+
+	return s.first, s.rest != nil, nil
+}
+
+// Rest returns the sequence without its first item, which is empty if the sequence is empty.
+func (s *Seq) Rest() (*Seq, error) {
+	if err := s.realize(); err != nil {
+		return nil, err
+	}
+
+	if s.rest == nil {
+		return EmptySeq(), nil
+	}
+
+	return s.rest, nil
+}
+
+// Each calls fn with every item until it returns false.
+func (s *Seq) Each(fn func(item *Value) (bool, error)) error {
+	for {
+		if err := s.realize(); err != nil {
+			return err
+		}
+
+		if s.rest == nil {
+			return nil
+		}
+
+		if more, err := fn(s.first); err != nil || !more {
+			return err
+		}
+
+		s = s.rest
+	}
+}
+
+// IsIterable returns true for the values Iterate accepts.
+func IsIterable(v *Value) bool {
+	return IsSequence(v) || v.Type == SeqValue
+}
+
+// Iterate calls fn with every item of a list, vector or lazy sequence until it returns false.
+// This is how values are realized by for and other functions that accept all of them.
+func Iterate(v *Value, fn func(item *Value) (bool, error)) error {
+	if v.Type == SeqValue {
+		return v.Seq.Each(fn)
+	}
+
+	for _, item := range Items(v) {
+		if more, err := fn(item); err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ToSeq returns a list, vector or lazy sequence as a lazy sequence.
+func ToSeq(v *Value) *Seq {
+	if v.Type == SeqValue {
+		return v.Seq
+	}
+
+	return NewListSeq(Items(v))
+}
@@ -0,0 +1,45 @@
+package runtime
+
+// Lists and vectors are both sequences. The functions in this file let the list namespace work on either,
+// functions that return a changed sequence return the same kind of sequence they were given.
+
+func IsSequence(v *Value) bool {
+	return v.Type == ListValue || v.Type == VectorValue
+}
+
+// Items returns the items of a list or vector in a new slice.
+func Items(v *Value) []*Value {
+	if v.Type == VectorValue {
+		return v.Vector.Values()
+	}
+
+	return v.List.Values()
+}
+
+// SequenceLen returns the number of items in a list or vector.
+func SequenceLen(v *Value) int {
+	if v.Type == VectorValue {
+		return v.Vector.Len()
+	}
+
+	return v.List.Len()
+}
+
+// SequenceGet returns the item at index i, which should be in bounds, of a list or vector.
+// It takes O(i) for a list.
+func SequenceGet(v *Value, i int) *Value {
+	if v.Type == VectorValue {
+		return v.Vector.Get(i)
+	}
+
+	return v.List.Get(i)
+}
+
+// NewSequenceOf returns a vector with the given items if like is a vector, and a list otherwise.
+func NewSequenceOf(like *Value, items []*Value) *Value {
+	if like.Type == VectorValue {
+		return NewVectorValue(NewVector(items))
+	}
+
+	return NewListValueOf(items)
+}
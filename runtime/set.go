@@ -0,0 +1,115 @@
+package runtime
+
+import "sort"
+
+// Set is a set of values, hashed with Value.Hash and compared with Value.Equals.
+// Sets are immutable once they're in a value, functions that change a set work on a copy.
+type Set struct {
+	buckets map[uint64][]*Value
+	size    int
+}
+
+func NewSet() *Set {
+	return &Set{buckets: make(map[uint64][]*Value)}
+}
+
+func (s *Set) Len() int {
+	return s.size
+}
+
+func (s *Set) Contains(v *Value) bool {
+	for _, item := range s.buckets[v.Hash()] {
+		if item.Equals(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Insert adds a value, it returns false if the set already contains it.
+func (s *Set) Insert(v *Value) bool {
+	hash := v.Hash()
+
+	for _, item := range s.buckets[hash] {
+		if item.Equals(v) {
+			return false
+		}
+	}
+
+	s.buckets[hash] = append(s.buckets[hash], v)
+	s.size++
+
+	return true
+}
+
+// Remove removes a value, it returns false if the set doesn't contain it.
+func (s *Set) Remove(v *Value) bool {
+	hash := v.Hash()
+	bucket := s.buckets[hash]
+
+	for i, item := range bucket {
+		if item.Equals(v) {
+			if len(bucket) == 1 {
+				delete(s.buckets, hash)
+			} else {
+				s.buckets[hash] = append(bucket[:i:i], bucket[i+1:]...)
+			}
+
+			s.size--
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Set) Copy() *Set {
+	other := &Set{buckets: make(map[uint64][]*Value, len(s.buckets)), size: s.size}
+
+	for hash, bucket := range s.buckets {
+		other.buckets[hash] = append([]*Value{}, bucket...)
+	}
+
+	return other
+}
+
+// Each calls fn with every value, in no particular order.
+func (s *Set) Each(fn func(v *Value)) {
+	for _, bucket := range s.buckets {
+		for _, item := range bucket {
+			fn(item)
+		}
+	}
+}
+
+// Values returns the values in a deterministic order, sorted with Compare.
+func (s *Set) Values() []*Value {
+	values := make([]*Value, 0, s.size)
+
+	s.Each(func(v *Value) {
+		values = append(values, v)
+	})
+
+	sort.Slice(values, func(i, j int) bool {
+		return Compare(values[i], values[j]) < 0
+	})
+
+	return values
+}
+
+// IsSubset checks if all values of s are in other.
+func (s *Set) IsSubset(other *Set) bool {
+	if s.size > other.size {
+		return false
+	}
+
+	subset := true
+
+	s.Each(func(v *Value) {
+		subset = subset && other.Contains(v)
+	})
+
+	return subset
+}
@@ -1,8 +1,18 @@
 package runtime
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/raoulvdberge/risp/lexer"
 	"github.com/raoulvdberge/risp/parser"
+	"math"
 	"math/big"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -22,7 +32,19 @@ const (
 	FunctionValue
 	NilValue
 	QuotedValue
-	AnyValue // used in arguments.go, to validate *any* argument
+	CharValue
+	RegexValue
+	ErrorValue
+	PortValue
+	InstantValue
+	DurationValue
+	GeneratorValue
+	BytesValue
+	SetValue
+	SeqValue
+	VectorValue
+	SequenceValue // used in arguments.go, to validate lists and vectors
+	AnyValue      // used in arguments.go, to validate *any* argument
 )
 
 func (t ValueType) String() string {
@@ -43,30 +65,154 @@ func (t ValueType) String() string {
 		return "nil"
 	case QuotedValue:
 		return "quoted"
+	case CharValue:
+		return "char"
+	case RegexValue:
+		return "regex"
+	case ErrorValue:
+		return "error"
+	case PortValue:
+		return "port"
+	case InstantValue:
+		return "instant"
+	case DurationValue:
+		return "duration"
+	case GeneratorValue:
+		return "generator"
+	case BytesValue:
+		return "bytes"
+	case SetValue:
+		return "set"
+	case SeqValue:
+		return "lazy-seq"
+	case VectorValue:
+		return "vector"
+	case SequenceValue:
+		return "sequence"
 	default:
 		return "?"
 	}
 }
 
+// NumberKind describes how a number value is stored.
+// Exact numbers are contagious: an operation on an exact and an inexact number gives an inexact number.
+type NumberKind int
+
+const (
+	ExactNumber    NumberKind = iota // exact integers and rationals, stored in Number
+	FloatNumber                      // inexact float64, stored in Float
+	BigFloatNumber                   // inexact arbitrary precision floats, stored in BigFloat
+)
+
 type Value struct {
-	Type     ValueType
-	Str      string
-	Number   *big.Rat
-	Boolean  bool
-	Keyword  string
-	List     []*Value
-	Function *Function
-	Quoted   parser.Node
+	Type       ValueType
+	Str        string
+	NumberKind NumberKind
+	Number     *big.Rat
+	Float      float64
+	BigFloat   *big.Float
+	Boolean    bool
+	Keyword    string
+	List       *List
+	Function   *Function
+	Quoted     parser.Node
+	Char       rune
+	Regex      *regexp.Regexp
+	Port       *Port
+	Instant    time.Time
+	Duration   time.Duration
+	Generator  *rand.Rand
+	Bytes      []byte
+	Set        *Set
+	Seq        *Seq
+	Vector     *Vector
 }
 
 func (v *Value) NumberToFloat64() float64 {
-	f, _ := v.Number.Float64()
+	switch v.NumberKind {
+	case FloatNumber:
+		return v.Float
+	case BigFloatNumber:
+		f, _ := v.BigFloat.Float64()
+
+		return f
+	default:
+		f, _ := v.Number.Float64()
 
-	return f
+		return f
+	}
 }
 
+// NumberToInt64 truncates the number towards zero. Values that don't fit in an int64 wrap around,
+// use NumberToBigInt or IntegerArgument where exactness matters.
 func (v *Value) NumberToInt64() int64 {
-	return int64(v.NumberToFloat64())
+	return v.NumberToBigInt().Int64()
+}
+
+// NumberToBigInt truncates the number towards zero. Infinities and NaN give 0.
+func (v *Value) NumberToBigInt() *big.Int {
+	switch v.NumberKind {
+	case FloatNumber, BigFloatNumber:
+		if !v.NumberIsFinite() {
+			return new(big.Int)
+		}
+
+		i, _ := v.numberToBigFloat(0).Int(nil)
+
+		return i
+	default:
+		if v.Number.IsInt() {
+			return new(big.Int).Set(v.Number.Num())
+		}
+
+		return new(big.Int).Quo(v.Number.Num(), v.Number.Denom())
+	}
+}
+
+// NumberToRat converts the number to an exact rational.
+// It returns an error for infinities and NaN, which have no exact representation.
+func (v *Value) NumberToRat() (*big.Rat, error) {
+	if v.NumberKind == ExactNumber {
+		return v.Number, nil
+	}
+
+	if !v.NumberIsFinite() {
+		return nil, fmt.Errorf("%s has no exact representation", v)
+	}
+
+	r, _ := v.numberToBigFloat(0).Rat(nil)
+
+	return r, nil
+}
+
+func (v *Value) NumberIsInteger() bool {
+	switch v.NumberKind {
+	case FloatNumber:
+		return v.NumberIsFinite() && v.Float == math.Trunc(v.Float)
+	case BigFloatNumber:
+		return v.BigFloat.IsInt()
+	default:
+		return v.Number.IsInt()
+	}
+}
+
+func (v *Value) NumberIsExact() bool {
+	return v.NumberKind == ExactNumber
+}
+
+func (v *Value) NumberIsFinite() bool {
+	switch v.NumberKind {
+	case FloatNumber:
+		return !math.IsInf(v.Float, 0) && !math.IsNaN(v.Float)
+	case BigFloatNumber:
+		return !v.BigFloat.IsInf()
+	default:
+		return true
+	}
+}
+
+func (v *Value) NumberIsNaN() bool {
+	return v.NumberKind == FloatNumber && math.IsNaN(v.Float)
 }
 
 func (v *Value) String() string {
@@ -74,13 +220,7 @@ func (v *Value) String() string {
 	case StringValue:
 		return v.Str
 	case NumberValue:
-		prec := 4
-
-		if v.Number.Denom().Cmp(big.NewInt(1)) == 0 {
-			prec = 0
-		}
-
-		return v.Number.FloatString(prec)
+		return v.numberString()
 	case BooleanValue:
 		if v.Boolean {
 			return "t"
@@ -94,10 +234,10 @@ func (v *Value) String() string {
 	case ListValue:
 		s := "("
 
-		for i, item := range v.List {
+		for i, item := range v.List.Values() {
 			s += item.String()
 
-			if i != len(v.List)-1 {
+			if i != v.List.Len()-1 {
 				s += " "
 			}
 		}
@@ -109,11 +249,106 @@ func (v *Value) String() string {
 		return "nil"
 	case QuotedValue:
 		return v.Quoted.String()
+	case CharValue:
+		return string(v.Char)
+	case RegexValue:
+		return "<regex " + v.Regex.String() + ">"
+	case ErrorValue:
+		return "<error :" + v.Keyword + " " + v.Str + ">"
+	case PortValue:
+		return "<port " + v.Port.Name + ">"
+	case InstantValue:
+		return v.Instant.Format(time.RFC3339Nano)
+	case DurationValue:
+		return v.Duration.String()
+	case BytesValue:
+		if len(v.Bytes) == 0 {
+			return "#x\"\""
+		}
+
+		return strings.TrimSuffix(hex.Dump(v.Bytes), "\n")
+	case SetValue:
+		s := "#{"
+
+		for i, item := range v.Set.Values() {
+			if i > 0 {
+				s += " "
+			}
+
+			s += item.String()
+		}
+
+		return s + "}"
+	case VectorValue:
+		s := "["
+
+		for i, item := range v.Vector.Values() {
+			if i > 0 {
+				s += " "
+			}
+
+			s += item.String()
+		}
+
+		return s + "]"
 	default:
 		return "<" + v.Type.String() + ">"
 	}
 }
 
+// ReadableString returns the value as it would be written in source code: strings are quoted and
+// escaped, and chars are written as char literals. String returns the value for display instead.
+func (v *Value) ReadableString() string {
+	switch v.Type {
+	case StringValue:
+		return strconv.Quote(v.Str)
+	case CharValue:
+		return "#\\" + lexer.CharName(v.Char)
+	case BytesValue:
+		return "#x\"" + hex.EncodeToString(v.Bytes) + "\""
+	case QuotedValue:
+		return "'" + v.Quoted.String()
+	case ListValue:
+		s := "("
+
+		for i, item := range v.List.Values() {
+			s += item.ReadableString()
+
+			if i != v.List.Len()-1 {
+				s += " "
+			}
+		}
+
+		return s + ")"
+	case SetValue:
+		s := "#{"
+
+		for i, item := range v.Set.Values() {
+			if i > 0 {
+				s += " "
+			}
+
+			s += item.ReadableString()
+		}
+
+		return s + "}"
+	case VectorValue:
+		s := "["
+
+		for i, item := range v.Vector.Values() {
+			if i > 0 {
+				s += " "
+			}
+
+			s += item.ReadableString()
+		}
+
+		return s + "]"
+	default:
+		return v.String()
+	}
+}
+
 func (v *Value) Copy() *Value {
 	if v.Type == NilValue {
 		return Nil
@@ -125,19 +360,53 @@ func (v *Value) Copy() *Value {
 	case StringValue:
 		other.Str = v.Str
 	case NumberValue:
+		other.NumberKind = v.NumberKind
 		other.Number = v.Number
+		other.Float = v.Float
+		other.BigFloat = v.BigFloat
 	case BooleanValue:
 		other.Boolean = v.Boolean
 	case KeywordValue:
 		other.Keyword = v.Keyword
 	case ListValue:
-		for _, item := range v.List {
-			other.List = append(other.List, item.Copy())
+		// the cells are immutable, but the items are copied so changing an item through a reference
+		// doesn't change it in the copy
+		items := v.List.Values()
+
+		for i, item := range items {
+			items[i] = item.Copy()
 		}
+
+		other.List = NewList(items)
 	case FunctionValue:
-		other.Function = v.Function.Copy()
+		// functions can't be changed, so copies share the function and keep its identity
+		other.Function = v.Function
 	case QuotedValue:
 		other.Quoted = v.Quoted
+	case CharValue:
+		other.Char = v.Char
+	case RegexValue:
+		other.Regex = v.Regex
+	case ErrorValue:
+		other.Keyword = v.Keyword
+		other.Str = v.Str
+	case PortValue:
+		other.Port = v.Port
+	case InstantValue:
+		other.Instant = v.Instant
+	case DurationValue:
+		other.Duration = v.Duration
+	case GeneratorValue:
+		other.Generator = v.Generator
+	case BytesValue:
+		other.Bytes = v.Bytes
+	case SetValue:
+		other.Set = v.Set
+	case SeqValue:
+		other.Seq = v.Seq
+	case VectorValue:
+		// vectors are persistent, so copies can share them
+		other.Vector = v.Vector
 	}
 
 	return other
@@ -152,29 +421,74 @@ func (v *Value) Equals(other *Value) bool {
 	case StringValue:
 		return v.Str == other.Str
 	case NumberValue:
-		return v.Number.Cmp(other.Number) == 0
+		if v.NumberIsExact() != other.NumberIsExact() {
+			return false
+		}
+
+		if v.NumberIsNaN() || other.NumberIsNaN() {
+			return v.NumberIsNaN() && other.NumberIsNaN()
+		}
+
+		cmp, _ := CompareNumbers(v, other)
+
+		return cmp == 0
 	case BooleanValue:
 		return v.Boolean == other.Boolean
 	case KeywordValue:
 		return v.Keyword == other.Keyword
 	case ListValue:
-		if len(v.List) != len(other.List) {
+		if v.List.Len() != other.List.Len() {
 			return false
 		}
 
-		for i, v := range v.List {
-			if !v.Equals(other.List[i]) {
+		otherItems := other.List.Values()
+
+		for i, item := range v.List.Values() {
+			if !item.Equals(otherItems[i]) {
 				return false
 			}
 		}
 
 		return true
 	case FunctionValue:
-		return &v.Function == &other.Function
+		return v.Function == other.Function
 	case NilValue:
 		return true
 	case QuotedValue:
 		return v.Quoted.Name() == other.Quoted.Name() && v.Quoted.String() == other.Quoted.String()
+	case CharValue:
+		return v.Char == other.Char
+	case RegexValue:
+		return v.Regex.String() == other.Regex.String()
+	case ErrorValue:
+		return v.Keyword == other.Keyword && v.Str == other.Str
+	case PortValue:
+		return v.Port == other.Port
+	case InstantValue:
+		return v.Instant.Equal(other.Instant)
+	case DurationValue:
+		return v.Duration == other.Duration
+	case GeneratorValue:
+		return v.Generator == other.Generator
+	case BytesValue:
+		return bytes.Equal(v.Bytes, other.Bytes)
+	case SetValue:
+		return v.Set.Len() == other.Set.Len() && v.Set.IsSubset(other.Set)
+	case SeqValue:
+		// sequences can be infinite, so they're compared by identity
+		return v.Seq == other.Seq
+	case VectorValue:
+		if v.Vector.Len() != other.Vector.Len() {
+			return false
+		}
+
+		for i := 0; i < v.Vector.Len(); i++ {
+			if !v.Vector.Get(i).Equals(other.Vector.Get(i)) {
+				return false
+			}
+		}
+
+		return true
 	default:
 		return false
 	}
@@ -184,15 +498,96 @@ func NewStringValue(value string) *Value {
 	return &Value{Type: StringValue, Str: value}
 }
 
+func NewCharValue(value rune) *Value {
+	return &Value{Type: CharValue, Char: value}
+}
+
+func NewRegexValue(value *regexp.Regexp) *Value {
+	return &Value{Type: RegexValue, Regex: value}
+}
+
+// NewErrorValue returns an error value, which functions return for failures that a script can handle.
+// The kind is a keyword like :not-found, the message describes the failure.
+func NewErrorValue(kind string, message string) *Value {
+	return &Value{Type: ErrorValue, Keyword: kind, Str: message}
+}
+
+func NewPortValue(value *Port) *Value {
+	return &Value{Type: PortValue, Port: value}
+}
+
+func NewInstantValue(value time.Time) *Value {
+	return &Value{Type: InstantValue, Instant: value}
+}
+
+func NewDurationValue(value time.Duration) *Value {
+	return &Value{Type: DurationValue, Duration: value}
+}
+
+func NewGeneratorValue(value *rand.Rand) *Value {
+	return &Value{Type: GeneratorValue, Generator: value}
+}
+
+func NewBytesValue(value []byte) *Value {
+	return &Value{Type: BytesValue, Bytes: value}
+}
+
+func NewSetValue(value *Set) *Value {
+	return &Value{Type: SetValue, Set: value}
+}
+
+func NewSeqValue(value *Seq) *Value {
+	return &Value{Type: SeqValue, Seq: value}
+}
+
+func NewVectorValue(value *Vector) *Value {
+	return &Value{Type: VectorValue, Vector: value}
+}
+
 func NewKeywordValue(value string) *Value {
 	return &Value{Type: KeywordValue, Keyword: value}
 }
 
-func NewNumberValueFromString(value string) *Value {
-	number := big.NewRat(0, 1)
-	number.SetString(value)
+// NewNumberValueFromString converts a number literal as accepted by the lexer to a number value.
+// Besides the syntax accepted by ParseNumber, literals can be prefixed by #e (exact) or #i (inexact)
+// and the inexact special values +inf.0, -inf.0 and +nan.0 are supported.
+func NewNumberValueFromString(value string) (*Value, error) {
+	exactness := ""
+
+	if strings.HasPrefix(value, "#") && len(value) > 2 {
+		exactness, value = value[1:2], value[2:]
+	}
+
+	var special float64
+
+	switch value {
+	case "+inf.0":
+		special = math.Inf(1)
+	case "-inf.0":
+		special = math.Inf(-1)
+	case "+nan.0", "-nan.0":
+		special = math.NaN()
+	default:
+		number, err := ParseNumber(value)
 
-	return NewNumberValueFromRat(number)
+		if err != nil {
+			return nil, err
+		}
+
+		if exactness == "i" {
+			f, _ := number.Float64()
+
+			return NewNumberValueFromFloat64(f), nil
+		}
+
+		return NewNumberValueFromRat(number), nil
+	}
+
+	if exactness == "e" {
+		return nil, fmt.Errorf("%s has no exact representation", value)
+	}
+
+	return NewNumberValueFromFloat64(special), nil
 }
 
 func NewNumberValueFromRat(value *big.Rat) *Value {
@@ -200,10 +595,15 @@ func NewNumberValueFromRat(value *big.Rat) *Value {
 }
 
 func NewNumberValueFromFloat64(value float64) *Value {
-	number := big.NewRat(0, 1)
-	number.SetFloat64(value)
+	return &Value{Type: NumberValue, NumberKind: FloatNumber, Float: value}
+}
 
-	return &Value{Type: NumberValue, Number: number}
+func NewNumberValueFromBigFloat(value *big.Float) *Value {
+	return &Value{Type: NumberValue, NumberKind: BigFloatNumber, BigFloat: value}
+}
+
+func NewNumberValueFromBigInt(value *big.Int) *Value {
+	return &Value{Type: NumberValue, Number: new(big.Rat).SetInt(value)}
 }
 
 func NewNumberValueFromInt64(value int64) *Value {
@@ -221,6 +621,16 @@ func NewListValue() *Value {
 	return &Value{Type: ListValue}
 }
 
+// NewListValueOf returns a list value of the given items.
+func NewListValueOf(items []*Value) *Value {
+	return &Value{Type: ListValue, List: NewList(items)}
+}
+
+// NewListValueFrom returns a list value that shares the cells of a list.
+func NewListValueFrom(value *List) *Value {
+	return &Value{Type: ListValue, List: value}
+}
+
 func NewQuotedValue(node parser.Node) *Value {
 	return &Value{Type: QuotedValue, Quoted: node}
 }
@@ -0,0 +1,255 @@
+package runtime
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	vectorMask  = vectorWidth - 1
+)
+
+// Vector is a persistent vector: a trie with 32 children per node, and a tail of up to 32 items that's
+// kept outside the trie so pushing and popping are cheap. Vectors are never changed: Push, Pop and Set
+// return a new vector that shares everything but the changed path with the old one, so values can share
+// vectors instead of copying them. Get and Set take O(log32 n), Push and Pop are amortized O(1), Drop is O(1).
+type Vector struct {
+	// size counts the dropped items too, start is the index of the first item that isn't dropped.
+	size  int
+	start int
+	shift uint
+	root  *vectorNode
+	tail  []*Value
+}
+
+// vectorNode has children, or items if it's a leaf.
+type vectorNode struct {
+	children []*vectorNode
+	items    []*Value
+}
+
+func (n *vectorNode) clone() *vectorNode {
+	return &vectorNode{
+		children: append([]*vectorNode{}, n.children...),
+		items:    append([]*Value{}, n.items...),
+	}
+}
+
+func EmptyVector() *Vector {
+	return &Vector{shift: vectorBits, root: &vectorNode{}}
+}
+
+func NewVector(items []*Value) *Vector {
+	v := EmptyVector()
+
+	for _, item := range items {
+		v = v.Push(item)
+	}
+
+	return v
+}
+
+func (v *Vector) Len() int {
+	return v.size - v.start
+}
+
+// tailOffset returns the index of the first item in the tail.
+func (v *Vector) tailOffset() int {
+	if v.size < vectorWidth {
+		return 0
+	}
+
+	return ((v.size - 1) >> vectorBits) << vectorBits
+}
+
+// leaf returns the items of the leaf or tail that contains index i.
+func (v *Vector) leaf(i int) []*Value {
+	if i >= v.tailOffset() {
+		return v.tail
+	}
+
+	node := v.root
+
+	for level := v.shift; level > 0; level -= vectorBits {
+		node = node.children[(i>>level)&vectorMask]
+	}
+
+	return node.items
+}
+
+// Get returns the item at index i, which should be in bounds.
+func (v *Vector) Get(i int) *Value {
+	i += v.start
+
+	return v.leaf(i)[i&vectorMask]
+}
+
+// Set returns a vector with the item at index i, which should be in bounds, replaced.
+func (v *Vector) Set(i int, value *Value) *Vector {
+	i += v.start
+
+	if i >= v.tailOffset() {
+		tail := append([]*Value{}, v.tail...)
+		tail[i&vectorMask] = value
+
+		return &Vector{size: v.size, start: v.start, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	return &Vector{size: v.size, start: v.start, shift: v.shift, root: v.setPath(v.shift, v.root, i, value), tail: v.tail}
+}
+
+func (v *Vector) setPath(level uint, node *vectorNode, i int, value *Value) *vectorNode {
+	result := node.clone()
+
+	if level == 0 {
+		result.items[i&vectorMask] = value
+	} else {
+		index := (i >> level) & vectorMask
+		result.children[index] = v.setPath(level-vectorBits, node.children[index], i, value)
+	}
+
+	return result
+}
+
+// Push returns a vector with an item added at the end.
+func (v *Vector) Push(value *Value) *Vector {
+	if v.size-v.tailOffset() < vectorWidth {
+		tail := make([]*Value, len(v.tail), len(v.tail)+1)
+		copy(tail, v.tail)
+
+		return &Vector{size: v.size + 1, start: v.start, shift: v.shift, root: v.root, tail: append(tail, value)}
+	}
+
+	// the tail is full, move it into the trie
+	tailNode := &vectorNode{items: v.tail}
+	shift := v.shift
+
+	var root *vectorNode
+
+	if (v.size >> vectorBits) > (1 << v.shift) {
+		// the trie is full, add a level
+		root = &vectorNode{children: []*vectorNode{v.root, newVectorPath(v.shift, tailNode)}}
+		shift += vectorBits
+	} else {
+		root = v.pushTail(v.shift, v.root, tailNode)
+	}
+
+	return &Vector{size: v.size + 1, start: v.start, shift: shift, root: root, tail: []*Value{value}}
+}
+
+func (v *Vector) pushTail(level uint, parent *vectorNode, tailNode *vectorNode) *vectorNode {
+	result := parent.clone()
+	index := ((v.size - 1) >> level) & vectorMask
+
+	child := tailNode
+
+	if level > vectorBits {
+		if index < len(parent.children) {
+			child = v.pushTail(level-vectorBits, parent.children[index], tailNode)
+		} else {
+			child = newVectorPath(level-vectorBits, tailNode)
+		}
+	}
+
+	if index < len(result.children) {
+		result.children[index] = child
+	} else {
+		result.children = append(result.children, child)
+	}
+
+	return result
+}
+
+// newVectorPath returns node wrapped in as many single child nodes as needed to reach level.
+func newVectorPath(level uint, node *vectorNode) *vectorNode {
+	if level == 0 {
+		return node
+	}
+
+	return &vectorNode{children: []*vectorNode{newVectorPath(level-vectorBits, node)}}
+}
+
+// Pop returns a vector without its last item, the vector shouldn't be empty.
+func (v *Vector) Pop() *Vector {
+	if v.Len() == 1 {
+		return EmptyVector()
+	}
+
+	if v.size-v.tailOffset() > 1 {
+		tail := append([]*Value{}, v.tail[:len(v.tail)-1]...)
+
+		return &Vector{size: v.size - 1, start: v.start, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	// the tail becomes empty, the last leaf of the trie becomes the new tail
+	tail := v.leaf(v.size - 2)
+	root := v.popTail(v.shift, v.root)
+	shift := v.shift
+
+	if root == nil {
+		root = &vectorNode{}
+	}
+
+	if shift > vectorBits && len(root.children) == 1 {
+		root = root.children[0]
+		shift -= vectorBits
+	}
+
+	return &Vector{size: v.size - 1, start: v.start, shift: shift, root: root, tail: tail}
+}
+
+// popTail returns node without its last leaf, or nil if nothing is left.
+func (v *Vector) popTail(level uint, node *vectorNode) *vectorNode {
+	index := ((v.size - 2) >> level) & vectorMask
+
+	if level > vectorBits {
+		child := v.popTail(level-vectorBits, node.children[index])
+
+		if child == nil && index == 0 {
+			return nil
+		}
+
+		result := node.clone()
+
+		if child == nil {
+			result.children = result.children[:index]
+		} else {
+			result.children[index] = child
+		}
+
+		return result
+	}
+
+	if index == 0 {
+		return nil
+	}
+
+	result := node.clone()
+	result.children = result.children[:index]
+
+	return result
+}
+
+// Drop returns a vector without its first n items, n should be at most the length. The dropped items
+// stay in the trie, they're skipped by moving the start.
+func (v *Vector) Drop(n int) *Vector {
+	if n == v.Len() {
+		return EmptyVector()
+	}
+
+	return &Vector{size: v.size, start: v.start + n, shift: v.shift, root: v.root, tail: v.tail}
+}
+
+// Values returns the items of the vector in a new slice.
+func (v *Vector) Values() []*Value {
+	values := make([]*Value, 0, v.Len())
+
+	for i := v.start &^ vectorMask; i < v.size; i += vectorWidth {
+		leaf := v.leaf(i)
+
+		if i < v.start {
+			leaf = leaf[v.start-i:]
+		}
+
+		values = append(values, leaf...)
+	}
+
+	return values
+}
@@ -0,0 +1,135 @@
+package set
+
+import "github.com/raoulvdberge/risp/runtime"
+
+// Sets are written as #{1 2 3}. Functions that change a set return a new set.
+var Symbols = runtime.Symtab{
+	"set?":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setIsSet, "set?"))),
+	"from-list":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setFromList, "from-list"))),
+	"to-list":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setToList, "to-list"))),
+	"size":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setSize, "size"))),
+	"contains":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setContains, "contains"))),
+	"add":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setAddOrRemove, "add"))),
+	"remove":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setAddOrRemove, "remove"))),
+	"union":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setCombine, "union"))),
+	"intersection": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setCombine, "intersection"))),
+	"difference":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setCombine, "difference"))),
+	"subset?":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(setIsSubset, "subset?"))),
+}
+
+func setIsSet(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.SetValue), nil
+}
+
+// setFromList returns a set of the items of a list or vector, without duplicates.
+func setFromList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SequenceValue); err != nil {
+		return nil, err
+	}
+
+	s := runtime.NewSet()
+
+	for _, item := range runtime.Items(context.Args[0]) {
+		s.Insert(item)
+	}
+
+	return runtime.NewSetValue(s), nil
+}
+
+// setToList returns the values of a set in the order they're printed in.
+func setToList(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SetValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewListValueOf(context.Args[0].Set.Values()), nil
+}
+
+func setSize(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SetValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(context.Args[0].Set.Len())), nil
+}
+
+func setContains(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SetValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Set.Contains(context.Args[1])), nil
+}
+
+// setAddOrRemove returns a set with any number of values added or removed.
+func setAddOrRemove(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 || context.Args[0].Type != runtime.SetValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a set followed by values", context.Name)
+	}
+
+	s := context.Args[0].Set.Copy()
+
+	for _, value := range context.Args[1:] {
+		if context.Name == "add" {
+			s.Insert(value)
+		} else {
+			s.Remove(value)
+		}
+	}
+
+	return runtime.NewSetValue(s), nil
+}
+
+// setCombine returns the union or intersection of one or more sets, or the values of the first set that aren't in the others.
+func setCombine(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 1 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected one or more sets", context.Name)
+	}
+
+	for i, arg := range context.Args {
+		if arg.Type != runtime.SetValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be of type %s, got %s", context.Name, i+1, runtime.SetValue, arg.Type)
+		}
+	}
+
+	first, others := context.Args[0].Set, context.Args[1:]
+
+	if context.Name == "union" {
+		s := first.Copy()
+
+		for _, other := range others {
+			other.Set.Each(func(v *runtime.Value) {
+				s.Insert(v)
+			})
+		}
+
+		return runtime.NewSetValue(s), nil
+	}
+
+	s := runtime.NewSet()
+
+	first.Each(func(v *runtime.Value) {
+		for _, other := range others {
+			if other.Set.Contains(v) != (context.Name == "intersection") {
+				return
+			}
+		}
+
+		s.Insert(v)
+	})
+
+	return runtime.NewSetValue(s), nil
+}
+
+// setIsSubset checks if all values of the first set are in the second set.
+func setIsSubset(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.SetValue, runtime.SetValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Set.IsSubset(context.Args[1].Set)), nil
+}
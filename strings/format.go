@@ -0,0 +1,1203 @@
+package strings
+
+import (
+	"fmt"
+	"github.com/raoulvdberge/risp/lexer"
+	"github.com/raoulvdberge/risp/runtime"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The format language is modelled after Common Lisp's format. A directive is written as
+// ~[params][:][@]char, where params is a comma separated list of integers, 'c for a character,
+// v for a parameter taken from the arguments or # for the amount of remaining arguments.
+//
+//   ~a ~s          display and readable printing: ~mincol,colinc,minpad,padcharA
+//   ~c             a char, ~:c writes its name and ~@c writes it readably
+//   ~d ~b ~o ~x    an integer in radix 10, 2, 8 or 16: ~mincol,padchar,commachar,intervalD
+//   ~r             an integer in any radix: ~radix,mincol,padchar,commachar,intervalR
+//   ~f ~e ~$       fixed, scientific and monetary notation: ~w,d,k,overflowchar,padcharF,
+//                  ~w,d,e,k,overflowchar,padcharE and ~d,n,w,padchar$
+//   ~% ~& ~~       newline, fresh line and a literal tilde
+//   ~p             plural suffix, ~:p reuses the previous argument and ~@p writes y or ies
+//   ~*             skips an argument, ~:* goes back and ~n@* goes to argument n
+//   ~[...~;...~]   conditionals, ~:[false~;true~], ~@[...~] and ~:; for the default clause
+//   ~{...~}        iteration over a list, ~@{ over the remaining arguments, ~:{ over sublists
+//   ~(...~)        case conversion, ~:( capitalizes words, ~@( the first word, ~:@( uppercases
+//   ~^             stops the enclosing iteration or the whole format when no arguments remain
+//   ~<newline>     ignores the newline and following whitespace
+//
+// A ~ that isn't followed by a directive displays the next argument like ~a, as a bare ~ did before
+// format had directives, so "~ and ~" still works. Use ~a in new code, since "~s" now prints readably.
+
+type formatParamKind int
+
+const (
+	formatParamNone formatParamKind = iota
+	formatParamInt
+	formatParamChar
+	formatParamArgument
+	formatParamRemaining
+)
+
+type formatParam struct {
+	kind  formatParamKind
+	value int
+	char  rune
+}
+
+type formatDirective struct {
+	char       rune
+	pos        int
+	params     []formatParam
+	colon      bool
+	at         bool
+	clauses    [][]formatItem // the bodies of ~[, ~{ and ~(
+	hasDefault bool           // the last clause of ~[ was introduced by ~:;
+	closeColon bool           // ~{ was closed with ~:}, so the body runs at least once
+}
+
+type formatItem struct {
+	text      string
+	directive *formatDirective
+}
+
+// formatEscape is returned by ~^ to stop the enclosing iteration or the whole format.
+type formatEscape struct {
+	all bool
+}
+
+func (e *formatEscape) Error() string {
+	return "~^ escape"
+}
+
+// Format formats args according to the directives in format.
+func Format(format string, args []*runtime.Value) (string, error) {
+	p := &formatParser{format: []rune(format)}
+
+	items, end, err := p.parseItems()
+
+	if err != nil {
+		return "", err
+	}
+
+	if end != nil {
+		if end.char == ';' {
+			return "", fmt.Errorf("~; at position %d outside of ~[", end.pos)
+		}
+
+		return "", fmt.Errorf("~%c at position %d without matching opening directive", end.char, end.pos)
+	}
+
+	f := &formatter{args: args}
+	out := &strings.Builder{}
+
+	if err := f.run(items, out); err != nil {
+		if _, isEscape := err.(*formatEscape); !isEscape {
+			return "", err
+		}
+	}
+
+	return out.String(), nil
+}
+
+// formatDirectives are the characters that end a directive.
+const formatDirectives = "ascdbxorfe$%&~p*^[]{}();\n"
+
+type formatParser struct {
+	format []rune
+	pos    int
+}
+
+// parseItems parses text and directives until the end of the format or a ~;, ~], ~} or ~), which is returned.
+func (p *formatParser) parseItems() ([]formatItem, *formatDirective, error) {
+	var items []formatItem
+	var text []rune
+
+	flush := func() {
+		if len(text) > 0 {
+			items = append(items, formatItem{text: string(text)})
+			text = nil
+		}
+	}
+
+	for p.pos < len(p.format) {
+		if p.format[p.pos] != '~' {
+			text = append(text, p.format[p.pos])
+			p.pos++
+
+			continue
+		}
+
+		d, err := p.parseDirective()
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch d.char {
+		case ';', ']', '}', ')':
+			flush()
+
+			return items, d, nil
+		case '\n':
+			if d.at {
+				text = append(text, '\n')
+			}
+
+			if !d.colon {
+				for p.pos < len(p.format) && p.format[p.pos] != '\n' && unicode.IsSpace(p.format[p.pos]) {
+					p.pos++
+				}
+			}
+
+			continue
+		case '[', '{', '(':
+			if err := p.parseClauses(d); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		flush()
+
+		items = append(items, formatItem{directive: d})
+	}
+
+	flush()
+
+	return items, nil, nil
+}
+
+func (p *formatParser) parseClauses(d *formatDirective) error {
+	closing := map[rune]rune{'[': ']', '{': '}', '(': ')'}[d.char]
+
+	for {
+		items, end, err := p.parseItems()
+
+		if err != nil {
+			return err
+		}
+
+		if end == nil {
+			return fmt.Errorf("unterminated ~%c at position %d", d.char, d.pos)
+		}
+
+		d.clauses = append(d.clauses, items)
+
+		if end.char == ';' {
+			if d.char != '[' {
+				return fmt.Errorf("~; at position %d outside of ~[", end.pos)
+			}
+
+			if d.hasDefault {
+				return fmt.Errorf("~; at position %d follows the default clause", end.pos)
+			}
+
+			d.hasDefault = end.colon
+
+			continue
+		}
+
+		if end.char != closing {
+			return fmt.Errorf("~%c at position %d does not close ~%c at position %d", end.char, end.pos, d.char, d.pos)
+		}
+
+		d.closeColon = end.colon
+
+		return nil
+	}
+}
+
+func (p *formatParser) parseDirective() (*formatDirective, error) {
+	d := &formatDirective{pos: p.pos}
+
+	p.pos++
+
+	for {
+		param, err := p.parseParam(d)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if p.pos < len(p.format) && p.format[p.pos] == ',' {
+			d.params = append(d.params, param)
+			p.pos++
+
+			continue
+		}
+
+		if param.kind != formatParamNone {
+			d.params = append(d.params, param)
+		}
+
+		break
+	}
+
+	for p.pos < len(p.format) && (p.format[p.pos] == ':' || p.format[p.pos] == '@') {
+		if p.format[p.pos] == ':' {
+			d.colon = true
+		} else {
+			d.at = true
+		}
+
+		p.pos++
+	}
+
+	if p.pos >= len(p.format) || !strings.ContainsRune(formatDirectives, unicode.ToLower(p.format[p.pos])) {
+		// a bare ~, what follows it is text
+		p.pos = d.pos + 1
+
+		return &formatDirective{char: 'a', pos: d.pos}, nil
+	}
+
+	d.char = unicode.ToLower(p.format[p.pos])
+
+	p.pos++
+
+	return d, nil
+}
+
+func (p *formatParser) parseParam(d *formatDirective) (formatParam, error) {
+	if p.pos >= len(p.format) {
+		return formatParam{}, nil
+	}
+
+	r := p.format[p.pos]
+
+	switch {
+	case r == '\'':
+		if p.pos+1 >= len(p.format) {
+			return formatParam{}, fmt.Errorf("expected a character after ' in directive at position %d", d.pos)
+		}
+
+		p.pos += 2
+
+		return formatParam{kind: formatParamChar, char: p.format[p.pos-1]}, nil
+	case r == 'v' || r == 'V':
+		p.pos++
+
+		return formatParam{kind: formatParamArgument}, nil
+	case r == '#':
+		p.pos++
+
+		return formatParam{kind: formatParamRemaining}, nil
+	case isDigit(r) || ((r == '+' || r == '-') && p.pos+1 < len(p.format) && isDigit(p.format[p.pos+1])):
+		start := p.pos
+
+		p.pos++
+
+		for p.pos < len(p.format) && isDigit(p.format[p.pos]) {
+			p.pos++
+		}
+
+		value, err := strconv.Atoi(string(p.format[start:p.pos]))
+
+		if err != nil {
+			return formatParam{}, fmt.Errorf("parameter %s in directive at position %d is out of range", string(p.format[start:p.pos]), d.pos)
+		}
+
+		return formatParam{kind: formatParamInt, value: value}, nil
+	default:
+		return formatParam{}, nil
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+type formatter struct {
+	args   []*runtime.Value
+	pos    int
+	parent *formatter // the formatter over the sublists in ~:{, used by ~:^
+}
+
+func (f *formatter) run(items []formatItem, out *strings.Builder) error {
+	for _, item := range items {
+		if item.directive == nil {
+			out.WriteString(item.text)
+
+			continue
+		}
+
+		if err := f.directive(item.directive, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *formatter) next(d *formatDirective) (*runtime.Value, error) {
+	if f.pos >= len(f.args) {
+		return nil, fmt.Errorf("~%c at position %d: not enough arguments", d.char, d.pos)
+	}
+
+	f.pos++
+
+	return f.args[f.pos-1], nil
+}
+
+// resolveParams replaces v and # parameters with their values.
+func (f *formatter) resolveParams(d *formatDirective) ([]formatParam, error) {
+	params := make([]formatParam, len(d.params))
+
+	for i, param := range d.params {
+		switch param.kind {
+		case formatParamRemaining:
+			params[i] = formatParam{kind: formatParamInt, value: len(f.args) - f.pos}
+		case formatParamArgument:
+			arg, err := f.next(d)
+
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case arg.Type == runtime.NilValue:
+				params[i] = formatParam{kind: formatParamNone}
+			case arg.Type == runtime.CharValue:
+				params[i] = formatParam{kind: formatParamChar, char: arg.Char}
+			case arg.Type == runtime.NumberValue && arg.NumberIsInteger():
+				params[i] = formatParam{kind: formatParamInt, value: int(arg.NumberToInt64())}
+			default:
+				return nil, fmt.Errorf("~%c at position %d: expected an integer or char parameter, got %s", d.char, d.pos, arg)
+			}
+		default:
+			params[i] = param
+		}
+	}
+
+	return params, nil
+}
+
+func intParam(d *formatDirective, params []formatParam, i int, def int) (int, error) {
+	if i >= len(params) || params[i].kind == formatParamNone {
+		return def, nil
+	}
+
+	if params[i].kind != formatParamInt {
+		return 0, fmt.Errorf("~%c at position %d: parameter %d should be an integer", d.char, d.pos, i+1)
+	}
+
+	return params[i].value, nil
+}
+
+func charParam(d *formatDirective, params []formatParam, i int, def rune) (rune, error) {
+	if i >= len(params) || params[i].kind == formatParamNone {
+		return def, nil
+	}
+
+	if params[i].kind != formatParamChar {
+		return 0, fmt.Errorf("~%c at position %d: parameter %d should be a character", d.char, d.pos, i+1)
+	}
+
+	return params[i].char, nil
+}
+
+func (f *formatter) directive(d *formatDirective, out *strings.Builder) error {
+	params, err := f.resolveParams(d)
+
+	if err != nil {
+		return err
+	}
+
+	switch d.char {
+	case 'a', 's':
+		return f.formatObject(d, params, out)
+	case 'c':
+		return f.formatChar(d, out)
+	case 'd':
+		return f.formatInteger(d, params, out, 10, 0)
+	case 'b':
+		return f.formatInteger(d, params, out, 2, 0)
+	case 'o':
+		return f.formatInteger(d, params, out, 8, 0)
+	case 'x':
+		return f.formatInteger(d, params, out, 16, 0)
+	case 'r':
+		radix, err := intParam(d, params, 0, 0)
+
+		if err != nil {
+			return err
+		}
+
+		if radix < 2 || radix > 36 {
+			return fmt.Errorf("~r at position %d: radix should be between 2 and 36", d.pos)
+		}
+
+		return f.formatInteger(d, params, out, radix, 1)
+	case 'f':
+		return f.formatFixed(d, params, out)
+	case 'e':
+		return f.formatExponential(d, params, out)
+	case '$':
+		return f.formatMonetary(d, params, out)
+	case '%', '~':
+		n, err := intParam(d, params, 0, 1)
+
+		if err != nil {
+			return err
+		}
+
+		out.WriteString(strings.Repeat(map[rune]string{'%': "\n", '~': "~"}[d.char], max(n, 0)))
+
+		return nil
+	case '&':
+		n, err := intParam(d, params, 0, 1)
+
+		if err != nil {
+			return err
+		}
+
+		if n > 0 && out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+			out.WriteString("\n")
+		}
+
+		out.WriteString(strings.Repeat("\n", max(n-1, 0)))
+
+		return nil
+	case 'p':
+		return f.formatPlural(d, out)
+	case '*':
+		return f.formatGoto(d, params)
+	case '^':
+		return f.formatEscape(d, params)
+	case '[':
+		return f.formatConditional(d, params, out)
+	case '{':
+		return f.formatIteration(d, params, out)
+	case '(':
+		return f.formatCase(d, out)
+	default:
+		return fmt.Errorf("unknown directive ~%c at position %d", d.char, d.pos)
+	}
+}
+
+// pad pads s with padchar until it is at least mincol characters wide.
+func pad(s string, mincol int, padchar rune, left bool) string {
+	n := mincol - utf8.RuneCountInString(s)
+
+	if n <= 0 {
+		return s
+	}
+
+	if left {
+		return strings.Repeat(string(padchar), n) + s
+	}
+
+	return s + strings.Repeat(string(padchar), n)
+}
+
+func (f *formatter) formatObject(d *formatDirective, params []formatParam, out *strings.Builder) error {
+	mincol, err := intParam(d, params, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	colinc, err := intParam(d, params, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	minpad, err := intParam(d, params, 2, 0)
+
+	if err != nil {
+		return err
+	}
+
+	padchar, err := charParam(d, params, 3, ' ')
+
+	if err != nil {
+		return err
+	}
+
+	if colinc < 1 {
+		return fmt.Errorf("~%c at position %d: colinc should be at least 1", d.char, d.pos)
+	}
+
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	s := arg.String()
+
+	if d.char == 's' {
+		s = arg.ReadableString()
+	}
+
+	width := utf8.RuneCountInString(s)
+	padding := max(minpad, 0)
+
+	for width+padding < mincol {
+		padding += colinc
+	}
+
+	out.WriteString(pad(s, width+padding, padchar, d.at))
+
+	return nil
+}
+
+func (f *formatter) formatChar(d *formatDirective, out *strings.Builder) error {
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	if arg.Type != runtime.CharValue {
+		return fmt.Errorf("~c at position %d: expected a char, got %s", d.pos, arg)
+	}
+
+	switch {
+	case d.at:
+		out.WriteString(arg.ReadableString())
+	case d.colon:
+		out.WriteString(lexer.CharName(arg.Char))
+	default:
+		out.WriteRune(arg.Char)
+	}
+
+	return nil
+}
+
+// formatInteger writes an exact integer in the given radix. Its parameters start at offset,
+// any other argument is written like ~a.
+func (f *formatter) formatInteger(d *formatDirective, params []formatParam, out *strings.Builder, radix int, offset int) error {
+	mincol, err := intParam(d, params, offset, 0)
+
+	if err != nil {
+		return err
+	}
+
+	padchar, err := charParam(d, params, offset+1, ' ')
+
+	if err != nil {
+		return err
+	}
+
+	commachar, err := charParam(d, params, offset+2, ',')
+
+	if err != nil {
+		return err
+	}
+
+	interval, err := intParam(d, params, offset+3, 3)
+
+	if err != nil {
+		return err
+	}
+
+	if interval < 1 {
+		return fmt.Errorf("~%c at position %d: comma interval should be at least 1", d.char, d.pos)
+	}
+
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	if arg.Type != runtime.NumberValue || !arg.NumberIsExact() || !arg.NumberIsInteger() {
+		out.WriteString(pad(arg.String(), mincol, padchar, true))
+
+		return nil
+	}
+
+	n := arg.NumberToBigInt()
+	s := new(big.Int).Abs(n).Text(radix)
+
+	if d.colon {
+		s = groupDigits(s, string(commachar), interval)
+	}
+
+	if n.Sign() < 0 {
+		s = "-" + s
+	} else if d.at {
+		s = "+" + s
+	}
+
+	out.WriteString(pad(s, mincol, padchar, true))
+
+	return nil
+}
+
+func groupDigits(s string, separator string, interval int) string {
+	var groups []string
+
+	for len(s) > interval {
+		groups = append([]string{s[len(s)-interval:]}, groups...)
+		s = s[:len(s)-interval]
+	}
+
+	return strings.Join(append([]string{s}, groups...), separator)
+}
+
+// floatField fits a formatted number in a field of width w, or fills the field with overflowchar
+// if it doesn't fit.
+func floatField(d *formatDirective, params []formatParam, s string, w int, overflowIndex int) (string, error) {
+	overflowchar, err := charParam(d, params, overflowIndex, 0)
+
+	if err != nil {
+		return "", err
+	}
+
+	padchar, err := charParam(d, params, overflowIndex+1, ' ')
+
+	if err != nil {
+		return "", err
+	}
+
+	if w > 0 && utf8.RuneCountInString(s) > w && overflowchar != 0 {
+		return strings.Repeat(string(overflowchar), w), nil
+	}
+
+	return pad(s, w, padchar, true), nil
+}
+
+func signed(s string, plus bool) string {
+	if plus && !strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "+") {
+		return "+" + s
+	}
+
+	return s
+}
+
+func (f *formatter) formatFixed(d *formatDirective, params []formatParam, out *strings.Builder) error {
+	w, err := intParam(d, params, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	digits, err := intParam(d, params, 1, -1)
+
+	if err != nil {
+		return err
+	}
+
+	scale, err := intParam(d, params, 2, 0)
+
+	if err != nil {
+		return err
+	}
+
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	if arg.Type != runtime.NumberValue {
+		out.WriteString(pad(arg.String(), w, ' ', true))
+
+		return nil
+	}
+
+	if scale != 0 {
+		factor := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(scale))), nil))
+
+		if scale < 0 {
+			factor.Inv(factor)
+		}
+
+		if arg, err = runtime.NumberArithmetic("*", arg, runtime.NewNumberValueFromRat(factor)); err != nil {
+			return err
+		}
+	}
+
+	format := runtime.NewNumberFormat()
+	format.Notation = "fixed"
+	format.Precision = digits
+
+	s, err := runtime.FormatNumber(arg, format)
+
+	if err != nil {
+		// exact numbers without a finite decimal representation are written like their inexact counterpart
+		s, err = runtime.FormatNumber(runtime.NewNumberValueFromFloat64(arg.NumberToFloat64()), format)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if digits < 0 && arg.NumberIsFinite() && !arg.NumberIsNaN() && !strings.Contains(s, ".") {
+		s += ".0"
+	}
+
+	s, err = floatField(d, params, signed(s, d.at), w, 3)
+
+	if err != nil {
+		return err
+	}
+
+	out.WriteString(s)
+
+	return nil
+}
+
+func (f *formatter) formatExponential(d *formatDirective, params []formatParam, out *strings.Builder) error {
+	w, err := intParam(d, params, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	digits, err := intParam(d, params, 1, -1)
+
+	if err != nil {
+		return err
+	}
+
+	exponentDigits, err := intParam(d, params, 2, 1)
+
+	if err != nil {
+		return err
+	}
+
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	if arg.Type != runtime.NumberValue {
+		out.WriteString(pad(arg.String(), w, ' ', true))
+
+		return nil
+	}
+
+	format := runtime.NewNumberFormat()
+	format.Notation = "scientific"
+	format.Precision = digits
+
+	s, err := runtime.FormatNumber(arg, format)
+
+	if err != nil {
+		return err
+	}
+
+	if arg.NumberIsFinite() && !arg.NumberIsNaN() {
+		mantissa, exponent, _ := strings.Cut(s, "e")
+
+		if !strings.Contains(mantissa, ".") {
+			mantissa += ".0"
+		}
+
+		exponentValue := strings.TrimLeft(exponent[1:], "0")
+
+		if exponentValue == "" {
+			exponentValue = "0"
+		}
+
+		s = mantissa + "e" + exponent[:1] + pad(exponentValue, exponentDigits, '0', true)
+	}
+
+	s, err = floatField(d, params, signed(s, d.at), w, 4)
+
+	if err != nil {
+		return err
+	}
+
+	out.WriteString(s)
+
+	return nil
+}
+
+func (f *formatter) formatMonetary(d *formatDirective, params []formatParam, out *strings.Builder) error {
+	digits, err := intParam(d, params, 0, 2)
+
+	if err != nil {
+		return err
+	}
+
+	minIntegerDigits, err := intParam(d, params, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	w, err := intParam(d, params, 2, 0)
+
+	if err != nil {
+		return err
+	}
+
+	padchar, err := charParam(d, params, 3, ' ')
+
+	if err != nil {
+		return err
+	}
+
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	if arg.Type != runtime.NumberValue {
+		out.WriteString(pad(arg.String(), w, ' ', true))
+
+		return nil
+	}
+
+	format := runtime.NewNumberFormat()
+	format.Notation = "fixed"
+	format.Precision = max(digits, 0)
+
+	s, err := runtime.FormatNumber(arg, format)
+
+	if err != nil {
+		return err
+	}
+
+	sign := ""
+
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	} else if d.at {
+		sign = "+"
+	}
+
+	integerPart, _, _ := strings.Cut(s, ".")
+	s = strings.Repeat("0", max(minIntegerDigits-len(integerPart), 0)) + s
+
+	if d.colon {
+		out.WriteString(sign + pad(s, w-len(sign), padchar, true))
+	} else {
+		out.WriteString(pad(sign+s, w, padchar, true))
+	}
+
+	return nil
+}
+
+func (f *formatter) formatPlural(d *formatDirective, out *strings.Builder) error {
+	if d.colon {
+		if f.pos == 0 {
+			return fmt.Errorf("~:p at position %d: there is no previous argument", d.pos)
+		}
+
+		f.pos--
+	}
+
+	arg, err := f.next(d)
+
+	if err != nil {
+		return err
+	}
+
+	one := false
+
+	if arg.Type == runtime.NumberValue {
+		cmp, ok := runtime.CompareNumbers(arg, runtime.NewNumberValueFromInt64(1))
+		one = ok && cmp == 0
+	}
+
+	switch {
+	case d.at && one:
+		out.WriteString("y")
+	case d.at:
+		out.WriteString("ies")
+	case !one:
+		out.WriteString("s")
+	}
+
+	return nil
+}
+
+func (f *formatter) formatGoto(d *formatDirective, params []formatParam) error {
+	target := 0
+
+	if d.at {
+		n, err := intParam(d, params, 0, 0)
+
+		if err != nil {
+			return err
+		}
+
+		target = n
+	} else {
+		n, err := intParam(d, params, 0, 1)
+
+		if err != nil {
+			return err
+		}
+
+		if d.colon {
+			n = -n
+		}
+
+		target = f.pos + n
+	}
+
+	if target < 0 || target > len(f.args) {
+		return fmt.Errorf("~* at position %d: argument %d is out of range", d.pos, target)
+	}
+
+	f.pos = target
+
+	return nil
+}
+
+func (f *formatter) formatEscape(d *formatDirective, params []formatParam) error {
+	var values []int
+
+	for i := range params {
+		value, err := intParam(d, params, i, 0)
+
+		if err != nil {
+			return err
+		}
+
+		values = append(values, value)
+	}
+
+	var stop bool
+
+	switch len(values) {
+	case 0:
+		if d.colon {
+			if f.parent == nil {
+				return fmt.Errorf("~:^ at position %d outside of ~:{", d.pos)
+			}
+
+			stop = f.parent.pos >= len(f.parent.args)
+		} else {
+			stop = f.pos >= len(f.args)
+		}
+	case 1:
+		stop = values[0] == 0
+	case 2:
+		stop = values[0] == values[1]
+	default:
+		stop = values[0] <= values[1] && values[1] <= values[2]
+	}
+
+	if stop {
+		return &formatEscape{all: d.colon}
+	}
+
+	return nil
+}
+
+func truthy(v *runtime.Value) bool {
+	return v.Type != runtime.NilValue && !(v.Type == runtime.BooleanValue && !v.Boolean)
+}
+
+func (f *formatter) formatConditional(d *formatDirective, params []formatParam, out *strings.Builder) error {
+	switch {
+	case d.colon && d.at:
+		return fmt.Errorf("~:@[ at position %d is not supported", d.pos)
+	case d.colon:
+		if len(d.clauses) != 2 {
+			return fmt.Errorf("~:[ at position %d expects exactly two clauses", d.pos)
+		}
+
+		arg, err := f.next(d)
+
+		if err != nil {
+			return err
+		}
+
+		if truthy(arg) {
+			return f.run(d.clauses[1], out)
+		}
+
+		return f.run(d.clauses[0], out)
+	case d.at:
+		if len(d.clauses) != 1 {
+			return fmt.Errorf("~@[ at position %d expects exactly one clause", d.pos)
+		}
+
+		arg, err := f.next(d)
+
+		if err != nil {
+			return err
+		}
+
+		if truthy(arg) {
+			f.pos--
+
+			return f.run(d.clauses[0], out)
+		}
+
+		return nil
+	}
+
+	index, err := intParam(d, params, 0, -1)
+
+	if err != nil {
+		return err
+	}
+
+	if len(params) == 0 || params[0].kind == formatParamNone {
+		arg, err := f.next(d)
+
+		if err != nil {
+			return err
+		}
+
+		if arg.Type != runtime.NumberValue || !arg.NumberIsInteger() {
+			return fmt.Errorf("~[ at position %d: expected an integer, got %s", d.pos, arg)
+		}
+
+		index = int(arg.NumberToInt64())
+	}
+
+	clauses := len(d.clauses)
+
+	if d.hasDefault {
+		clauses--
+	}
+
+	if index >= 0 && index < clauses {
+		return f.run(d.clauses[index], out)
+	}
+
+	if d.hasDefault {
+		return f.run(d.clauses[clauses], out)
+	}
+
+	return nil
+}
+
+func (f *formatter) formatIteration(d *formatDirective, params []formatParam, out *strings.Builder) error {
+	limit, err := intParam(d, params, 0, -1)
+
+	if err != nil {
+		return err
+	}
+
+	items := &formatter{args: f.args, pos: f.pos}
+
+	if !d.at {
+		arg, err := f.next(d)
+
+		if err != nil {
+			return err
+		}
+
+		if arg.Type != runtime.ListValue {
+			return fmt.Errorf("~{ at position %d: expected a list, got %s", d.pos, arg)
+		}
+
+		items = &formatter{args: arg.List.Values()}
+	}
+
+	body := d.clauses[0]
+
+	for i := 0; limit < 0 || i < limit; i++ {
+		if items.pos >= len(items.args) && !(i == 0 && d.closeColon) {
+			break
+		}
+
+		start := items.pos
+
+		if d.colon {
+			sublist, err := items.next(d)
+
+			if err != nil {
+				return err
+			}
+
+			if sublist.Type != runtime.ListValue {
+				return fmt.Errorf("~:{ at position %d: expected a list of lists, got %s", d.pos, sublist)
+			}
+
+			err = (&formatter{args: sublist.List.Values(), parent: items}).run(body, out)
+
+			if escape, isEscape := err.(*formatEscape); isEscape {
+				if escape.all {
+					break
+				}
+			} else if err != nil {
+				return err
+			}
+		} else {
+			err := items.run(body, out)
+
+			if _, isEscape := err.(*formatEscape); isEscape {
+				break
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if items.pos == start && items.pos < len(items.args) && limit < 0 {
+			return fmt.Errorf("~{ at position %d: the body doesn't consume any arguments", d.pos)
+		}
+	}
+
+	if d.at {
+		f.pos = items.pos
+	}
+
+	return nil
+}
+
+func (f *formatter) formatCase(d *formatDirective, out *strings.Builder) error {
+	inner := &strings.Builder{}
+
+	err := f.run(d.clauses[0], inner)
+
+	if _, isEscape := err.(*formatEscape); err != nil && !isEscape {
+		return err
+	}
+
+	s := inner.String()
+
+	switch {
+	case d.colon && d.at:
+		s = strings.ToUpper(s)
+	case d.colon:
+		s = capitalize(s, true)
+	case d.at:
+		s = capitalize(s, false)
+	default:
+		s = strings.ToLower(s)
+	}
+
+	out.WriteString(s)
+
+	return err
+}
+
+// capitalize lowercases s and uppercases the first letter of the first word, or of every word if all is true.
+func capitalize(s string, all bool) string {
+	var b strings.Builder
+
+	inWord := false
+	capitalized := false
+
+	for _, r := range s {
+		isWordPart := unicode.IsLetter(r) || unicode.IsDigit(r)
+
+		if isWordPart && !inWord && (all || !capitalized) {
+			b.WriteRune(unicode.ToUpper(r))
+
+			capitalized = true
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+
+		inWord = isWordPart
+	}
+
+	return b.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
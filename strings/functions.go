@@ -3,26 +3,35 @@ package strings
 import (
 	"github.com/raoulvdberge/risp/runtime"
 	"github.com/raoulvdberge/risp/util"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 var Symbols = runtime.Symtab{
-	"range":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsRange, "range"))),
-	"trim":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsTrim, "trim"))),
-	"rune-at":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsRuneAt, "rune-at"))),
-	"length":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsLength, "length"))),
-	"format":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsFormat, "format"))),
-	"split":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsSplit, "split"))),
-	"replace":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsReplace, "replace"))),
-	"reverse":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsReverse, "reverse"))),
-	"contains":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsContains, "contains"))),
-	"lower":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsLower, "lower"))),
-	"upper":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsUpper, "upper"))),
-	"is-digit":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-digit"))),
-	"is-letter": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-letter"))),
-	"is-lower":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-lower"))),
-	"is-upper":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-upper"))),
+	"range":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsRange, "range"))),
+	"trim":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsTrim, "trim"))),
+	"rune-at":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsRuneAt, "rune-at"))),
+	"length":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsLength, "length"))),
+	"byte-length": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsByteLength, "byte-length"))),
+	"bytes":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsBytes, "bytes"))),
+	"normalize":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsNormalize, "normalize"))),
+	"fold":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsFold, "fold"))),
+	"width":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsWidth, "width"))),
+	"format":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsFormat, "format"))),
+	"split":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsSplit, "split"))),
+	"replace":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsReplace, "replace"))),
+	"reverse":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsReverse, "reverse"))),
+	"contains":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsContains, "contains"))),
+	"lower":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsLower, "lower"))),
+	"upper":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsUpper, "upper"))),
+	"is-digit":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-digit"))),
+	"is-letter":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-letter"))),
+	"is-lower":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-lower"))),
+	"is-upper":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(stringsCharacterCheck, "is-upper"))),
 }
 
 func stringsRange(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -30,9 +39,19 @@ func stringsRange(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, err
 	}
 
-	source := context.Args[0].Str
-	start := context.Args[1].NumberToInt64()
-	end := context.Args[2].NumberToInt64()
+	source := []rune(context.Args[0].Str)
+
+	start, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := runtime.Int64Argument(context, 2)
+
+	if err != nil {
+		return nil, err
+	}
 
 	sourceLen := int64(len(source))
 
@@ -40,9 +59,11 @@ func stringsRange(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, runtime.NewRuntimeError(context.Pos, "out of bounds (length is %d, trying to access %d:%d)", sourceLen, start, end)
 	}
 
-	result := source[start:end]
+	if start > end {
+		return nil, runtime.NewRuntimeError(context.Pos, "invalid range, start can't be higher than end (%d > %d)", start, end)
+	}
 
-	return runtime.NewStringValue(result), nil
+	return runtime.NewStringValue(string(source[start:end])), nil
 }
 
 func stringsTrim(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -60,9 +81,19 @@ func stringsRuneAt(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, err
 	}
 
-	source := context.Args[0].Str
-	index := context.Args[1].NumberToInt64()
-	return runtime.NewStringValue(string([]rune(source)[index])), nil
+	runes := []rune(context.Args[0].Str)
+
+	index, err := runtime.Int64Argument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 || index >= int64(len(runes)) {
+		return nil, runtime.NewRuntimeError(context.Pos, "index %d out of bounds (length is %d)", index, len(runes))
+	}
+
+	return runtime.NewCharValue(runes[index]), nil
 }
 
 func stringsLength(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -70,9 +101,86 @@ func stringsLength(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, err
 	}
 
+	return runtime.NewNumberValueFromInt64(int64(utf8.RuneCountInString(context.Args[0].Str))), nil
+}
+
+func stringsByteLength(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
 	return runtime.NewNumberValueFromInt64(int64(len(context.Args[0].Str))), nil
 }
 
+// stringsBytes returns the UTF-8 encoding of a string as a list of numbers.
+func stringsBytes(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	var items []*runtime.Value
+
+	for _, b := range []byte(context.Args[0].Str) {
+		items = append(items, runtime.NewNumberValueFromInt64(int64(b)))
+	}
+
+	return runtime.NewListValueOf(items), nil
+}
+
+func stringsNormalize(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	var form norm.Form
+
+	switch context.Args[1].Keyword {
+	case "nfc":
+		form = norm.NFC
+	case "nfd":
+		form = norm.NFD
+	case "nfkc":
+		form = norm.NFKC
+	case "nfkd":
+		form = norm.NFKD
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "unknown normalization form %s, expected :nfc, :nfd, :nfkc or :nfkd", context.Args[1])
+	}
+
+	return runtime.NewStringValue(form.String(context.Args[0].Str)), nil
+}
+
+// stringsFold applies Unicode case folding, which is meant for case-insensitive comparisons.
+func stringsFold(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewStringValue(cases.Fold().String(context.Args[0].Str)), nil
+}
+
+// stringsWidth returns the amount of columns a string takes up in a monospaced terminal.
+// East Asian wide and fullwidth characters take up two columns, combining marks and control characters none.
+func stringsWidth(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	columns := 0
+
+	for _, r := range context.Args[0].Str {
+		switch {
+		case unicode.IsControl(r), unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		case width.LookupRune(r).Kind() == width.EastAsianWide, width.LookupRune(r).Kind() == width.EastAsianFullwidth:
+			columns += 2
+		default:
+			columns++
+		}
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(columns)), nil
+}
+
 func stringsFormat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
 	if len(context.Args) < 1 {
 		return nil, runtime.NewRuntimeError(context.Pos, "missing format specifier")
@@ -82,19 +190,13 @@ func stringsFormat(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, runtime.NewRuntimeError(context.Pos, "format specifier should be a string")
 	}
 
-	format := context.Args[0].Str
+	s, err := Format(context.Args[0].Str, context.Args[1:])
 
-	args := strings.Count(format, "~")
-
-	if len(context.Args)-1 != args {
-		return nil, runtime.NewRuntimeError(context.Pos, "format specifier expected %d arguments, got %d", args, len(context.Args)-1)
-	}
-
-	for _, item := range context.Args[1:] {
-		format = strings.Replace(format, "~", item.String(), 1)
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
 	}
 
-	return runtime.NewStringValue(format), nil
+	return runtime.NewStringValue(s), nil
 }
 
 func stringsSplit(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -102,13 +204,13 @@ func stringsSplit(context *runtime.FunctionCallContext) (*runtime.Value, error)
 		return nil, err
 	}
 
-	parts := runtime.NewListValue()
+	var parts []*runtime.Value
 
 	for _, item := range strings.Split(context.Args[0].Str, context.Args[1].Str) {
-		parts.List = append(parts.List, runtime.NewStringValue(item))
+		parts = append(parts, runtime.NewStringValue(item))
 	}
 
-	return parts, nil
+	return runtime.NewListValueOf(parts), nil
 }
 
 func stringsReplace(context *runtime.FunctionCallContext) (*runtime.Value, error) {
@@ -123,7 +225,13 @@ func stringsReplace(context *runtime.FunctionCallContext) (*runtime.Value, error
 			return nil, err
 		}
 	} else {
-		n = int(context.Args[3].NumberToInt64())
+		count, err := runtime.Int64Argument(context, 3)
+
+		if err != nil {
+			return nil, err
+		}
+
+		n = int(count)
 	}
 
 	source := context.Args[0].Str
@@ -166,16 +274,10 @@ func stringsUpper(context *runtime.FunctionCallContext) (*runtime.Value, error)
 }
 
 func stringsCharacterCheck(context *runtime.FunctionCallContext) (*runtime.Value, error) {
-	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+	if err := runtime.ValidateArguments(context, runtime.CharValue); err != nil {
 		return nil, err
 	}
 
-	s := context.Args[0].Str
-
-	if len(s) != 1 {
-		return nil, runtime.NewRuntimeError(context.Pos, "expected string that has 1 character, got %d character(s)", len(s))
-	}
-
 	var callback func(rune) bool
 
 	switch context.Name {
@@ -189,5 +291,5 @@ func stringsCharacterCheck(context *runtime.FunctionCallContext) (*runtime.Value
 		callback = unicode.IsUpper
 	}
 
-	return runtime.BooleanValueFor(callback(rune(s[0]))), nil
+	return runtime.BooleanValueFor(callback(context.Args[0].Char)), nil
 }
@@ -0,0 +1,324 @@
+package system
+
+import (
+	"bytes"
+	"errors"
+	"github.com/raoulvdberge/risp/fs"
+	"github.com/raoulvdberge/risp/runtime"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var Symbols = runtime.Symtab{
+	"args":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemArgs, "args"))),
+	"getenv":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemGetenv, "getenv"))),
+	"setenv":   runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemSetenv, "setenv"))),
+	"unsetenv": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemUnsetenv, "unsetenv"))),
+	"environ":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemEnviron, "environ"))),
+	"exit":     runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemExit, "exit"))),
+	"cwd":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemCwd, "cwd"))),
+	"chdir":    runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemChdir, "chdir"))),
+	"hostname": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemHostname, "hostname"))),
+	"pid":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemPid, "pid"))),
+	"run":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(systemRun, "run"))),
+}
+
+// args are the command line arguments after the script name.
+var args []string
+
+func SetArgs(a []string) {
+	args = a
+}
+
+func systemArgs(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	var l []*runtime.Value
+
+	for _, arg := range args {
+		l = append(l, runtime.NewStringValue(arg))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// systemGetenv returns the value of an environment variable, or nil if it isn't set.
+func systemGetenv(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	value, ok := os.LookupEnv(context.Args[0].Str)
+
+	if !ok {
+		return runtime.Nil, nil
+	}
+
+	return runtime.NewStringValue(value), nil
+}
+
+func systemSetenv(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	if err := os.Setenv(context.Args[0].Str, context.Args[1].Str); err != nil {
+		return runtime.NewErrorValue("env", err.Error()), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func systemUnsetenv(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	if err := os.Unsetenv(context.Args[0].Str); err != nil {
+		return runtime.NewErrorValue("env", err.Error()), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+// systemEnviron returns the environment as a keyword list, sorted by name.
+func systemEnviron(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	env := os.Environ()
+
+	sort.Strings(env)
+
+	var l []*runtime.Value
+
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+
+		l = append(l, runtime.NewKeywordValue(name), runtime.NewStringValue(value))
+	}
+
+	return runtime.NewListValueOf(l), nil
+}
+
+func systemExit(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	code := int64(0)
+
+	if len(context.Args) > 0 {
+		if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+			return nil, err
+		}
+
+		n, err := runtime.Int64Argument(context, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		code = n
+	}
+
+	// os.Exit doesn't run deferred calls or return to main, so the ports are flushed here
+	if err := runtime.FlushPorts(); err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s", context.Name, err.Error())
+	}
+
+	os.Exit(int(code))
+
+	return runtime.Nil, nil
+}
+
+func systemCwd(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.Getwd()
+
+	if err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.NewStringValue(dir), nil
+}
+
+// systemChdir changes the working directory, which is used by subprocesses.
+// Like the paths in the fs namespace, a relative directory is resolved against the directory of the running script.
+func systemChdir(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	dir, err := fs.ResolvePath(context.Pos, context.Args[0].Str)
+
+	if err != nil {
+		return fs.ErrorValue(err), nil
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return fs.ErrorValue(err), nil
+	}
+
+	return runtime.Nil, nil
+}
+
+func systemHostname(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.NewStringValue(hostname), nil
+}
+
+func systemPid(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewNumberValueFromInt64(int64(os.Getpid())), nil
+}
+
+// systemRun runs a command with a list of arguments and waits for it to finish. It accepts the options
+// :stdin (a string piped to the command), :timeout (in seconds), :dir (the working directory) and
+// :env (a keyword list of extra environment variables). It returns a keyword list with :exit, :stdout and :stderr.
+// Running commands is not allowed in a sandbox, as they could access any path.
+func systemRun(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if len(context.Args) < 2 || context.Args[0].Type != runtime.StringValue || context.Args[1].Type != runtime.ListValue {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a command and a list of arguments", context.Name)
+	}
+
+	if fs.Sandbox() != "" {
+		return runtime.NewErrorValue("sandbox", "running commands is not allowed in a sandbox"), nil
+	}
+
+	var commandArgs []string
+
+	for _, arg := range context.Args[1].List.Values() {
+		commandArgs = append(commandArgs, arg.String())
+	}
+
+	cmd := exec.Command(context.Args[0].Str, commandArgs...)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	options := context.Args[2:]
+
+	if len(options)%2 != 0 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected options as keyword and value pairs", context.Name)
+	}
+
+	var timeout time.Duration
+
+	for i := 0; i < len(options); i += 2 {
+		key, value := options[i], options[i+1]
+
+		if key.Type != runtime.KeywordValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a keyword, got %s", context.Name, key.Type)
+		}
+
+		switch key.Keyword {
+		case "stdin", "dir":
+			if value.Type != runtime.StringValue {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a string", context.Name, key)
+			}
+
+			if key.Keyword == "stdin" {
+				cmd.Stdin = strings.NewReader(value.Str)
+			} else {
+				dir, err := fs.ResolvePath(context.Pos, value.Str)
+
+				if err != nil {
+					return fs.ErrorValue(err), nil
+				}
+
+				cmd.Dir = dir
+			}
+		case "timeout":
+			if value.Type != runtime.NumberValue || value.NumberToFloat64() <= 0 {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a positive number", context.Name, key)
+			}
+
+			timeout = time.Duration(value.NumberToFloat64() * float64(time.Second))
+		case "env":
+			if value.Type != runtime.ListValue || value.List.Len()%2 != 0 {
+				return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a keyword list", context.Name, key)
+			}
+
+			cmd.Env = os.Environ()
+			env := value.List.Values()
+
+			for j := 0; j < len(env); j += 2 {
+				if env[j].Type != runtime.KeywordValue {
+					return nil, runtime.NewRuntimeError(context.Pos, "%s: %s should be a keyword list", context.Name, key)
+				}
+
+				cmd.Env = append(cmd.Env, env[j].Keyword+"="+env[j+1].String())
+			}
+		default:
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: unknown option %s", context.Name, key)
+		}
+	}
+
+	if timeout > 0 {
+		// don't wait for processes started by the command that keep its output open
+		cmd.WaitDelay = time.Second
+	}
+
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return runtime.NewErrorValue("not-found", err.Error()), nil
+		}
+
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	var timedOut bool
+	var timedOutMutex sync.Mutex
+
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			timedOutMutex.Lock()
+			timedOut = true
+			timedOutMutex.Unlock()
+
+			cmd.Process.Kill()
+		})
+
+		defer timer.Stop()
+	}
+
+	err := cmd.Wait()
+
+	timedOutMutex.Lock()
+	defer timedOutMutex.Unlock()
+
+	if timedOut {
+		return runtime.NewErrorValue("timeout", context.Args[0].Str+": timed out after "+timeout.String()), nil
+	}
+
+	if _, isExitError := err.(*exec.ExitError); err != nil && !isExitError {
+		return runtime.NewIOErrorValue(err), nil
+	}
+
+	return runtime.NewListValueOf([]*runtime.Value{
+		runtime.NewKeywordValue("exit"), runtime.NewNumberValueFromInt64(int64(cmd.ProcessState.ExitCode())),
+		runtime.NewKeywordValue("stdout"), runtime.NewStringValue(stdout.String()),
+		runtime.NewKeywordValue("stderr"), runtime.NewStringValue(stderr.String()),
+	}), nil
+}
@@ -0,0 +1,625 @@
+package time
+
+import (
+	"github.com/raoulvdberge/risp/runtime"
+	"math/big"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones are available even if the system has no zone database
+)
+
+// Instants are points in time with a time zone, durations are lengths of time with nanosecond precision.
+// Functions that take a unit accept :nanosecond, :microsecond, :millisecond, :second, :minute, :hour, :day and :week,
+// in singular or plural; truncating an instant also accepts :month and :year. Days and weeks are 24 and 168 hours long.
+// Instants that are constructed, parsed or converted from Unix time are in UTC unless a zone is given.
+var Symbols = runtime.Symtab{
+	"now":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeNow, "now"))),
+	"monotonic":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeMonotonic, "monotonic"))),
+	"since":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeSince, "since"))),
+	"sleep":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeSleep, "sleep"))),
+	"instant":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeInstant, "instant"))),
+	"instant?":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeIsInstant, "instant?"))),
+	"parts":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeParts, "parts"))),
+	"parse":           runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeParse, "parse"))),
+	"format":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeFormat, "format"))),
+	"in-zone":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeInZone, "in-zone"))),
+	"zone":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeZone, "zone"))),
+	"duration":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeDuration, "duration"))),
+	"duration?":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeIsDuration, "duration?"))),
+	"parse-duration":  runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeParseDuration, "parse-duration"))),
+	"in-units":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeInUnits, "in-units"))),
+	"add":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeAdd, "add"))),
+	"sub":             runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeSub, "sub"))),
+	"add-date":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeAddDate, "add-date"))),
+	"compare":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeCompare, "compare"))),
+	"before?":         runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeCompare, "before?"))),
+	"after?":          runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeCompare, "after?"))),
+	"truncate":        runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeTruncate, "truncate"))),
+	"unix":            runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeUnix, "unix"))),
+	"unix-milli":      runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeUnix, "unix-milli"))),
+	"from-unix":       runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeFromUnix, "from-unix"))),
+	"from-unix-milli": runtime.NewSymbol(runtime.NewFunctionValue(runtime.NewBuiltinFunction(timeFromUnix, "from-unix-milli"))),
+}
+
+// layouts are the names that can be used instead of a layout string.
+var layouts = map[string]string{
+	"rfc3339":   time.RFC3339Nano,
+	"rfc1123":   time.RFC1123,
+	"rfc1123z":  time.RFC1123Z,
+	"rfc822":    time.RFC822,
+	"rfc822z":   time.RFC822Z,
+	"ansic":     time.ANSIC,
+	"unix-date": time.UnixDate,
+	"kitchen":   time.Kitchen,
+	"date":      time.DateOnly,
+	"time":      time.TimeOnly,
+	"datetime":  time.DateTime,
+}
+
+var units = map[string]time.Duration{
+	"nanosecond":  time.Nanosecond,
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"minute":      time.Minute,
+	"hour":        time.Hour,
+	"day":         24 * time.Hour,
+	"week":        7 * 24 * time.Hour,
+}
+
+// start is used by monotonic, it carries a monotonic clock reading.
+var start = time.Now()
+
+func unitArgument(context *runtime.FunctionCallContext, i int) (string, error) {
+	arg := context.Args[i]
+
+	if arg.Type == runtime.KeywordValue {
+		unit := strings.TrimSuffix(arg.Keyword, "s")
+
+		if _, ok := units[unit]; ok || unit == "month" || unit == "year" {
+			return unit, nil
+		}
+	}
+
+	return "", runtime.NewRuntimeError(context.Pos, "%s: argument %d should be a unit, got %s", context.Name, i+1, arg)
+}
+
+func layoutArgument(context *runtime.FunctionCallContext, i int) (string, error) {
+	arg := context.Args[i]
+
+	switch arg.Type {
+	case runtime.StringValue:
+		return arg.Str, nil
+	case runtime.KeywordValue:
+		if layout, ok := layouts[arg.Keyword]; ok {
+			return layout, nil
+		}
+	}
+
+	return "", runtime.NewRuntimeError(context.Pos, "%s: argument %d should be a layout string or name, got %s", context.Name, i+1, arg)
+}
+
+// loadLocation loads a zone from the embedded zone database, "Local" is the zone of the system.
+func loadLocation(name string) (*time.Location, *runtime.Value) {
+	location, err := time.LoadLocation(name)
+
+	if err != nil {
+		return nil, runtime.NewErrorValue("time", err.Error())
+	}
+
+	return location, nil
+}
+
+// secondsToDuration converts a number of seconds, or of the given unit, to a duration rounded to the nearest nanosecond.
+func secondsToDuration(context *runtime.FunctionCallContext, n *runtime.Value, unit time.Duration) (time.Duration, error) {
+	r, err := n.NumberToRat()
+
+	if err != nil {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: %s is not a finite number", context.Name, n)
+	}
+
+	r = new(big.Rat).Mul(r, new(big.Rat).SetInt64(int64(unit)))
+
+	f, _ := r.Float64()
+
+	if f >= float64(1<<63) || f < -float64(1<<63) {
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: %s is out of range for a duration", context.Name, n)
+	}
+
+	return time.Duration(roundRat(r)), nil
+}
+
+// roundRat rounds a rational number to the nearest integer, halves away from zero.
+func roundRat(r *big.Rat) int64 {
+	half := big.NewRat(1, 2)
+
+	if r.Sign() < 0 {
+		half.Neg(half)
+	}
+
+	r = new(big.Rat).Add(r, half)
+
+	return new(big.Int).Quo(r.Num(), r.Denom()).Int64()
+}
+
+// durationArgument returns argument i, which should be a duration or a number of seconds, as a duration.
+func durationArgument(context *runtime.FunctionCallContext, i int) (time.Duration, error) {
+	arg := context.Args[i]
+
+	switch arg.Type {
+	case runtime.DurationValue:
+		return arg.Duration, nil
+	case runtime.NumberValue:
+		return secondsToDuration(context, arg, time.Second)
+	default:
+		return 0, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be a duration or a number of seconds, got %s", context.Name, i+1, arg.Type)
+	}
+}
+
+func timeNow(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewInstantValue(time.Now()), nil
+}
+
+// timeMonotonic returns the time since the interpreter started, measured with a monotonic clock
+// that isn't affected by changes to the system clock.
+func timeMonotonic(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewDurationValue(time.Since(start)), nil
+}
+
+// timeSince returns the time elapsed since an instant. For instants returned by now, it's measured with a monotonic clock.
+func timeSince(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewDurationValue(time.Since(context.Args[0].Instant)), nil
+}
+
+func timeSleep(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	d, err := durationArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(d)
+
+	return runtime.Nil, nil
+}
+
+// timeInstant constructs an instant from a year, month and day, optionally followed by an hour, minute,
+// second and nanosecond and a zone name. Values outside their usual ranges are normalized, so October 32 is November 1.
+func timeInstant(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	args := context.Args
+	location := time.UTC
+
+	if len(args) > 0 && args[len(args)-1].Type == runtime.StringValue {
+		l, errValue := loadLocation(args[len(args)-1].Str)
+
+		if errValue != nil {
+			return errValue, nil
+		}
+
+		location = l
+		args = args[:len(args)-1]
+	}
+
+	if len(args) < 3 || len(args) > 7 {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected a year, month and day, optionally followed by an hour, minute, second, nanosecond and zone", context.Name)
+	}
+
+	parts := make([]int, 7)
+
+	for i := range args {
+		if args[i].Type != runtime.NumberValue {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: argument %d should be of type %s, got %s", context.Name, i+1, runtime.NumberValue, args[i].Type)
+		}
+
+		n, err := runtime.Int64Argument(context, i)
+
+		if err != nil {
+			return nil, err
+		}
+
+		parts[i] = int(n)
+	}
+
+	return runtime.NewInstantValue(time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], parts[6], location)), nil
+}
+
+func timeIsInstant(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.InstantValue), nil
+}
+
+// timeParts returns the parts of an instant in its zone as a keyword list with :year, :month, :day, :hour, :minute,
+// :second, :nanosecond, :weekday (a keyword like :monday), :yearday, :zone (the abbreviation) and :offset (in seconds).
+func timeParts(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue); err != nil {
+		return nil, err
+	}
+
+	t := context.Args[0].Instant
+	zone, offset := t.Zone()
+
+	var l []*runtime.Value
+
+	for _, part := range []struct {
+		name  string
+		value int
+	}{
+		{"year", t.Year()},
+		{"month", int(t.Month())},
+		{"day", t.Day()},
+		{"hour", t.Hour()},
+		{"minute", t.Minute()},
+		{"second", t.Second()},
+		{"nanosecond", t.Nanosecond()},
+	} {
+		l = append(l, runtime.NewKeywordValue(part.name), runtime.NewNumberValueFromInt64(int64(part.value)))
+	}
+
+	l = append(
+		l,
+		runtime.NewKeywordValue("weekday"), runtime.NewKeywordValue(strings.ToLower(t.Weekday().String())),
+		runtime.NewKeywordValue("yearday"), runtime.NewNumberValueFromInt64(int64(t.YearDay())),
+		runtime.NewKeywordValue("zone"), runtime.NewStringValue(zone),
+		runtime.NewKeywordValue("offset"), runtime.NewNumberValueFromInt64(int64(offset)),
+	)
+
+	return runtime.NewListValueOf(l), nil
+}
+
+// timeParse parses a string with a layout, which is a Go layout string like "2006-01-02 15:04" or the name of one,
+// like :rfc3339 or :date. If the string has no zone it's parsed in the zone given as the third argument, or UTC.
+func timeParse(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	location := time.UTC
+
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue, runtime.StringValue); err == nil {
+		l, errValue := loadLocation(context.Args[2].Str)
+
+		if errValue != nil {
+			return errValue, nil
+		}
+
+		location = l
+	} else if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	layout, err := layoutArgument(context, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := time.ParseInLocation(layout, context.Args[1].Str, location)
+
+	if err != nil {
+		return runtime.NewErrorValue("time", err.Error()), nil
+	}
+
+	return runtime.NewInstantValue(t), nil
+}
+
+func timeFormat(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	layout, err := layoutArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewStringValue(context.Args[0].Instant.Format(layout)), nil
+}
+
+// timeInZone returns the same instant in another zone, like "Europe/Amsterdam", "UTC" or "Local".
+func timeInZone(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	location, errValue := loadLocation(context.Args[1].Str)
+
+	if errValue != nil {
+		return errValue, nil
+	}
+
+	return runtime.NewInstantValue(context.Args[0].Instant.In(location)), nil
+}
+
+func timeZone(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.NewStringValue(context.Args[0].Instant.Location().String()), nil
+}
+
+// timeDuration returns a duration of a number of units, like (time:duration 90 :minutes).
+func timeDuration(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	unit, err := unitArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := units[unit]; !ok {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: a %s has no fixed length", context.Name, unit)
+	}
+
+	d, err := secondsToDuration(context, context.Args[0], units[unit])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewDurationValue(d), nil
+}
+
+func timeIsDuration(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	return runtime.BooleanValueFor(context.Args[0].Type == runtime.DurationValue), nil
+}
+
+// timeParseDuration parses a duration like "1h30m" or "250ms".
+func timeParseDuration(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.StringValue); err != nil {
+		return nil, err
+	}
+
+	d, err := time.ParseDuration(context.Args[0].Str)
+
+	if err != nil {
+		return runtime.NewErrorValue("time", err.Error()), nil
+	}
+
+	return runtime.NewDurationValue(d), nil
+}
+
+// timeInUnits returns the exact number of units in a duration, as a rational if it isn't a whole number.
+func timeInUnits(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.DurationValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	unit, err := unitArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := units[unit]; !ok {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: a %s has no fixed length", context.Name, unit)
+	}
+
+	return runtime.NewNumberValueFromRat(big.NewRat(int64(context.Args[0].Duration), int64(units[unit]))), nil
+}
+
+// timeAdd adds a duration to an instant or another duration.
+func timeAdd(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.DurationValue); err != nil {
+		return nil, err
+	}
+
+	a, d := context.Args[0], context.Args[1].Duration
+
+	switch a.Type {
+	case runtime.InstantValue:
+		return runtime.NewInstantValue(a.Instant.Add(d)), nil
+	case runtime.DurationValue:
+		return runtime.NewDurationValue(a.Duration + d), nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected an instant or a duration, got %s", context.Name, a.Type)
+	}
+}
+
+// timeSub subtracts a duration from an instant or another duration, or returns the duration between two instants.
+// The duration between instants that are too far apart is clamped to the largest duration, about 292 years.
+func timeSub(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	a, b := context.Args[0], context.Args[1]
+
+	switch {
+	case a.Type == runtime.InstantValue && b.Type == runtime.InstantValue:
+		return runtime.NewDurationValue(a.Instant.Sub(b.Instant)), nil
+	case a.Type == runtime.InstantValue && b.Type == runtime.DurationValue:
+		return runtime.NewInstantValue(a.Instant.Add(-b.Duration)), nil
+	case a.Type == runtime.DurationValue && b.Type == runtime.DurationValue:
+		return runtime.NewDurationValue(a.Duration - b.Duration), nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: cannot subtract a %s from a %s", context.Name, b.Type, a.Type)
+	}
+}
+
+// timeAddDate adds years, months and days to an instant in its zone, so adding a day across
+// a daylight saving time change keeps the time of day. Like instant, the result is normalized.
+func timeAddDate(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue, runtime.NumberValue, runtime.NumberValue, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	parts := make([]int, 3)
+
+	for i := range parts {
+		n, err := runtime.Int64Argument(context, i+1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		parts[i] = int(n)
+	}
+
+	return runtime.NewInstantValue(context.Args[0].Instant.AddDate(parts[0], parts[1], parts[2])), nil
+}
+
+// timeCompare compares two instants or two durations. compare returns -1, 0 or 1, before? and after? return a boolean.
+func timeCompare(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.AnyValue); err != nil {
+		return nil, err
+	}
+
+	a, b := context.Args[0], context.Args[1]
+
+	var cmp int
+
+	switch {
+	case a.Type == runtime.InstantValue && b.Type == runtime.InstantValue:
+		cmp = a.Instant.Compare(b.Instant)
+	case a.Type == runtime.DurationValue && b.Type == runtime.DurationValue:
+		switch {
+		case a.Duration < b.Duration:
+			cmp = -1
+		case a.Duration > b.Duration:
+			cmp = 1
+		}
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected two instants or two durations, got %s and %s", context.Name, a.Type, b.Type)
+	}
+
+	switch context.Name {
+	case "before?":
+		return runtime.BooleanValueFor(cmp < 0), nil
+	case "after?":
+		return runtime.BooleanValueFor(cmp > 0), nil
+	default:
+		return runtime.NewNumberValueFromInt64(int64(cmp)), nil
+	}
+}
+
+// timeTruncate truncates an instant to the start of a unit in its zone, weeks start on Monday.
+// A duration is truncated to a multiple of the unit.
+func timeTruncate(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.AnyValue, runtime.KeywordValue); err != nil {
+		return nil, err
+	}
+
+	unit, err := unitArgument(context, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	arg := context.Args[0]
+
+	switch arg.Type {
+	case runtime.DurationValue:
+		if _, ok := units[unit]; !ok {
+			return nil, runtime.NewRuntimeError(context.Pos, "%s: a %s has no fixed length", context.Name, unit)
+		}
+
+		return runtime.NewDurationValue(arg.Duration.Truncate(units[unit])), nil
+	case runtime.InstantValue:
+		return runtime.NewInstantValue(truncateInstant(arg.Instant, unit)), nil
+	default:
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: expected an instant or a duration, got %s", context.Name, arg.Type)
+	}
+}
+
+func truncateInstant(t time.Time, unit string) time.Time {
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	nanosecond := t.Nanosecond()
+
+	switch unit {
+	case "year":
+		month = time.January
+		fallthrough
+	case "month":
+		day = 1
+		fallthrough
+	case "day":
+		hour = 0
+		fallthrough
+	case "hour":
+		minute = 0
+		fallthrough
+	case "minute":
+		second = 0
+		fallthrough
+	case "second":
+		nanosecond = 0
+	case "week":
+		day -= (int(t.Weekday()) + 6) % 7
+		hour, minute, second, nanosecond = 0, 0, 0, 0
+	case "millisecond", "microsecond":
+		nanosecond -= nanosecond % int(units[unit])
+	}
+
+	return time.Date(year, month, day, hour, minute, second, nanosecond, t.Location())
+}
+
+// timeUnix returns the number of whole seconds (or milliseconds for unix-milli) since January 1, 1970 UTC.
+func timeUnix(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.InstantValue); err != nil {
+		return nil, err
+	}
+
+	t := context.Args[0].Instant
+
+	if context.Name == "unix-milli" {
+		return runtime.NewNumberValueFromInt64(t.UnixMilli()), nil
+	}
+
+	return runtime.NewNumberValueFromInt64(t.Unix()), nil
+}
+
+// timeFromUnix converts a number of seconds (or milliseconds for from-unix-milli) since January 1, 1970 UTC
+// to an instant in UTC. The number may have a fractional part.
+func timeFromUnix(context *runtime.FunctionCallContext) (*runtime.Value, error) {
+	if err := runtime.ValidateArguments(context, runtime.NumberValue); err != nil {
+		return nil, err
+	}
+
+	unit := time.Second
+
+	if context.Name == "from-unix-milli" {
+		unit = time.Millisecond
+	}
+
+	r, err := context.Args[0].NumberToRat()
+
+	if err != nil {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s is not a finite number", context.Name, context.Args[0])
+	}
+
+	nanoseconds := new(big.Rat).Mul(r, new(big.Rat).SetInt64(int64(unit)))
+	seconds := new(big.Int).Div(nanoseconds.Num(), new(big.Int).Mul(nanoseconds.Denom(), big.NewInt(int64(time.Second))))
+
+	if !seconds.IsInt64() {
+		return nil, runtime.NewRuntimeError(context.Pos, "%s: %s is out of range", context.Name, context.Args[0])
+	}
+
+	remainder := new(big.Rat).Sub(nanoseconds, new(big.Rat).SetInt(new(big.Int).Mul(seconds, big.NewInt(int64(time.Second)))))
+
+	return runtime.NewInstantValue(time.Unix(seconds.Int64(), roundRat(remainder)).UTC()), nil
+}
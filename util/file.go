@@ -3,12 +3,14 @@ package util
 import (
 	"io/ioutil"
 	"path"
+	"path/filepath"
 	"strings"
 )
 
 type File struct {
 	Path string
 	Name string
+	Dir  string // absolute directory of the file
 	Data string
 }
 
@@ -22,6 +24,12 @@ func NewFile(filePath string) (*File, error) {
 		return nil, err
 	}
 
+	file.Dir, err = filepath.Abs(filepath.Dir(file.Path))
+
+	if err != nil {
+		return nil, err
+	}
+
 	file.Data = string(data)
 
 	return file, nil
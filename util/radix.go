@@ -0,0 +1,18 @@
+package util
+
+// RadixPrefix returns the base and name of the radix prefix (0x, 0o or 0b) s starts with.
+// It returns base 10 if s has no radix prefix.
+func RadixPrefix(s string) (int, string) {
+	if len(s) >= 2 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			return 16, "hexadecimal"
+		case 'o', 'O':
+			return 8, "octal"
+		case 'b', 'B':
+			return 2, "binary"
+		}
+	}
+
+	return 10, "decimal"
+}